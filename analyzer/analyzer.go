@@ -0,0 +1,271 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/pkg/errors"
+)
+
+// IAnalyzer is used to analyze the fields (IFilterField) with the same name
+// which are combined into a single condition (i.e. all of them have to match).
+// It is injected into the IFilterBuilder as a dependency
+// and runs before the fields are merged.
+type IAnalyzer interface {
+	// Analyze returns error if the fields can never match at the same time
+	Analyze(fields ...field.IFilterField) error
+}
+
+// bound is a single range condition, e.g. $gt 10
+type bound struct {
+	operator string
+	value    interface{}
+}
+
+// contradictionAnalyzer detects the combinations of conditions no value can satisfy,
+// e.g. eq 5 and eq 6, gt 10 and lt 5 or in an empty list.
+// Only the values it can compare (numbers with numbers, strings with strings)
+// are checked against the ranges. The conditions of a field stored as an array are matched
+// by any of its elements (e.g. tags eq go and tags eq rust both match [go, rust]),
+// so the fields compared with a slice or an array by an operator other than in and nin
+// (whose operands are always lists) are only checked for empty lists. The analyzer
+// can not tell whether a field compared with single values is stored as an array in the documents though,
+// so it may report a false contradiction for such a field.
+type contradictionAnalyzer struct{}
+
+func (a *contradictionAnalyzer) Analyze(fields ...field.IFilterField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	name := fields[0].GetName()
+
+	var equal, notEqual []interface{}
+	var in, notIn [][]interface{}
+	var lower, upper []bound
+
+	for _, fld := range fields {
+		// merged fields have no operator of their own
+		if fld.GetOperator() == nil {
+			continue
+		}
+
+		value := fld.GetValue()
+		switch op := fld.GetOperator().ExternalName(); op {
+		case "eq":
+			equal = append(equal, value)
+		case "ne":
+			notEqual = append(notEqual, value)
+		case "in":
+			if values, ok := elements(value); ok {
+				in = append(in, values)
+			}
+		case "nin":
+			if values, ok := elements(value); ok {
+				notIn = append(notIn, values)
+			}
+		case "gt", "gte":
+			lower = append(lower, bound{operator: op, value: value})
+		case "lt", "lte":
+			upper = append(upper, bound{operator: op, value: value})
+		}
+	}
+
+	for _, values := range in {
+		if len(values) == 0 {
+			return errors.Errorf("field %s can not be in an empty list", name)
+		}
+	}
+
+	if array(fields) {
+		return nil
+	}
+
+	for _, l := range lower {
+		for _, u := range upper {
+			c, ok := compare(l.value, u.value)
+			if ok && (c > 0 || c == 0 && (l.operator == "gt" || u.operator == "lt")) {
+				return errors.Errorf("field %s can not be %s %v and %s %v",
+					name, l.operator, l.value, u.operator, u.value)
+			}
+		}
+	}
+
+	// the value of the field has to be one of the candidates,
+	// so it is enough to check whether any of them satisfies the rest of the conditions
+	var candidates []interface{}
+	switch {
+	case len(equal) > 0:
+		for _, value := range equal[1:] {
+			if !equals(equal[0], value) {
+				return errors.Errorf("field %s can not be equal to both %v and %v", name, equal[0], value)
+			}
+		}
+		candidates = equal[:1]
+	case len(in) > 0:
+		candidates = in[0]
+	default:
+		return nil
+	}
+
+	for _, candidate := range candidates {
+		if satisfies(candidate, notEqual, in, notIn, lower, upper) {
+			return nil
+		}
+	}
+
+	if len(equal) > 0 {
+		return errors.Errorf("field %s can not be equal to %v and satisfy its other conditions", name, equal[0])
+	}
+	return errors.Errorf("field %s has no value in its lists satisfying its other conditions", name)
+}
+
+// array checks whether the field is stored as an array, i.e. whether any of its fields
+// is compared with a slice or an array by an operator other than in and nin
+func array(fields []field.IFilterField) bool {
+	for _, fld := range fields {
+		if fld.GetOperator() == nil {
+			continue
+		}
+		switch fld.GetOperator().ExternalName() {
+		case "in", "nin":
+			continue
+		}
+		if fld.GetType() == reflect.Slice.String() || fld.GetType() == reflect.Array.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfies checks whether the value satisfies all of the conditions
+func satisfies(value interface{}, notEqual []interface{}, in [][]interface{},
+	notIn [][]interface{}, lower []bound, upper []bound) bool {
+	for _, other := range notEqual {
+		if equals(value, other) {
+			return false
+		}
+	}
+	for _, values := range in {
+		if !contains(values, value) {
+			return false
+		}
+	}
+	for _, values := range notIn {
+		if contains(values, value) {
+			return false
+		}
+	}
+	for _, b := range append(lower, upper...) {
+		c, ok := compare(value, b.value)
+		if !ok {
+			continue
+		}
+		switch b.operator {
+		case "gt":
+			if c <= 0 {
+				return false
+			}
+		case "gte":
+			if c < 0 {
+				return false
+			}
+		case "lt":
+			if c >= 0 {
+				return false
+			}
+		case "lte":
+			if c > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// elements returns the elements of a slice or array value
+func elements(value interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	values := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values = append(values, rv.Index(i).Interface())
+	}
+	return values, true
+}
+
+// contains checks whether the values contain the value
+func contains(values []interface{}, value interface{}) bool {
+	for _, other := range values {
+		if equals(other, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equals checks whether the values are equal, numbers of different types included
+func equals(a interface{}, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares two numbers or two strings.
+// It returns false if the values are not comparable.
+func compare(a interface{}, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// number converts any numeric value to float64
+func number(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// NewContradictionAnalyzer creates a new analyzer detecting contradicting conditions
+func NewContradictionAnalyzer() IAnalyzer {
+	return &contradictionAnalyzer{}
+}
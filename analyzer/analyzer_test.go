@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContradictionAnalyzer_Analyze(t *testing.T) {
+	newAge := func(value interface{}, op operator.IOperator) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "age", value, op, 0)
+	}
+	newTags := func(value interface{}, op operator.IOperator) field.IFilterField {
+		return field.NewFilterField("", reflect.Slice.String(), "tags", value, op, 0)
+	}
+
+	tests := []struct {
+		name    string
+		fields  []field.IFilterField
+		wantErr bool
+	}{
+		{
+			name:   "Equal values are satisfiable",
+			fields: []field.IFilterField{newAge(5, operator.EQOperator{}), newAge(5.0, operator.EQOperator{})},
+		},
+		{
+			name:    "Different equal values contradict",
+			fields:  []field.IFilterField{newAge(5, operator.EQOperator{}), newAge(6, operator.EQOperator{})},
+			wantErr: true,
+		},
+		{
+			name:    "Equal and not equal values contradict",
+			fields:  []field.IFilterField{newAge(5, operator.EQOperator{}), newAge(5, operator.NEOperator{})},
+			wantErr: true,
+		},
+		{
+			name:   "Overlapping range is satisfiable",
+			fields: []field.IFilterField{newAge(5, operator.GTEOperator{}), newAge(5, operator.LTEOperator{})},
+		},
+		{
+			name:    "Empty range contradicts",
+			fields:  []field.IFilterField{newAge(10, operator.GTOperator{}), newAge(5, operator.LTOperator{})},
+			wantErr: true,
+		},
+		{
+			name:    "Open range with the same bounds contradicts",
+			fields:  []field.IFilterField{newAge(5, operator.GTOperator{}), newAge(5, operator.LTEOperator{})},
+			wantErr: true,
+		},
+		{
+			name:    "Equal value out of range contradicts",
+			fields:  []field.IFilterField{newAge(3, operator.EQOperator{}), newAge(5, operator.GTOperator{})},
+			wantErr: true,
+		},
+		{
+			name:    "Empty list contradicts",
+			fields:  []field.IFilterField{newAge([]int{}, operator.INOperator{})},
+			wantErr: true,
+		},
+		{
+			name: "Lists with a common value are satisfiable",
+			fields: []field.IFilterField{
+				newAge([]int{1, 2, 3}, operator.INOperator{}),
+				newAge([]int{3, 4}, operator.INOperator{}),
+				newAge([]int{1}, operator.NINOperator{}),
+			},
+		},
+		{
+			name: "Lists without a common value contradict",
+			fields: []field.IFilterField{
+				newAge([]int{1, 2}, operator.INOperator{}),
+				newAge([]int{3, 4}, operator.INOperator{}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "List values out of range contradict",
+			fields: []field.IFilterField{
+				newAge([]int{1, 2}, operator.INOperator{}),
+				newAge(2, operator.GTOperator{}),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Equal value excluded by list contradicts",
+			fields:  []field.IFilterField{newAge(1, operator.EQOperator{}), newAge([]int{1}, operator.NINOperator{})},
+			wantErr: true,
+		},
+		{
+			name:   "Different values of an array field are satisfiable",
+			fields: []field.IFilterField{newTags("go", operator.EQOperator{}), newTags("rust", operator.EQOperator{})},
+		},
+		{
+			name:    "Empty list of an array field contradicts",
+			fields:  []field.IFilterField{newTags([]string{}, operator.INOperator{})},
+			wantErr: true,
+		},
+		{
+			name:   "Values of different types are not compared",
+			fields: []field.IFilterField{newAge("10", operator.GTOperator{}), newAge(5, operator.LTOperator{})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewContradictionAnalyzer().Analyze(tt.fields...)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestContradictionAnalyzer_Analyze_ScannedLists(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  interface{}
+		wantErr bool
+	}{
+		{
+			name: "List values out of range contradict",
+			filter: struct {
+				AgeIn []int `filter:"age" operator:"in"`
+				AgeGt int   `filter:"age" operator:"gt"`
+			}{AgeIn: []int{1, 2}, AgeGt: 5},
+			wantErr: true,
+		},
+		{
+			name: "List value in range is satisfiable",
+			filter: struct {
+				AgeIn []int `filter:"age" operator:"in"`
+				AgeGt int   `filter:"age" operator:"gt"`
+			}{AgeIn: []int{1, 6}, AgeGt: 5},
+		},
+		{
+			name: "Lists without a common value contradict",
+			filter: struct {
+				AgeIn     []int `filter:"age" operator:"in"`
+				AgeAlsoIn []int `filter:"age" operator:"in"`
+			}{AgeIn: []int{1, 2}, AgeAlsoIn: []int{3, 4}},
+			wantErr: true,
+		},
+		{
+			name: "Equal value excluded by list contradicts",
+			filter: struct {
+				AgeEq  int   `filter:"age" operator:"eq"`
+				AgeNin []int `filter:"age" operator:"nin"`
+			}{AgeEq: 1, AgeNin: []int{1}},
+			wantErr: true,
+		},
+		{
+			name: "Empty list contradicts",
+			filter: struct {
+				AgeIn []int `filter:"age" operator:"in"`
+			}{AgeIn: []int{}},
+			wantErr: true,
+		},
+	}
+
+	scan := scanner.NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "relation")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := scan.Scan(tt.filter, nil, 0)
+			assert.NoError(t, err)
+
+			err = NewContradictionAnalyzer().Analyze(fields...)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
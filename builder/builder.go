@@ -10,8 +10,15 @@
 package builder
 
 import (
+	"github.com/jobsearch-demos/mongo-filter-struct/analyzer"
 	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/policy"
+	"github.com/jobsearch-demos/mongo-filter-struct/projection"
+	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // IFilterBuilder is used to build bson filter for mongodb based on provided struct.
@@ -20,10 +27,49 @@ import (
 // them into a single bson.D object. It also provides convenience methods like add/remove fields.
 // To confirm to SRP, it delegates the responsibility of scanning struct fields to IScanner
 // and the responsibility of building bson.D from each field to IFilterField
+// The builder is immutable, the methods modifying it return the modified builder,
+// so their result has to be used instead of the builder they were called on.
 type IFilterBuilder interface {
 	// SetFields sets the list of fields for the filter.
 	SetFields(fields []field.IFilterField) IFilterBuilder
 
+	// SetCollection sets the collection the filter is run against.
+	// Fields with a relation are joined in the pipeline output.
+	SetCollection(collection string) IFilterBuilder
+
+	// SetJoinPolicy sets the policy used to join the relations that do not name one.
+	SetJoinPolicy(joinPolicy policy.IJoinPolicy) IFilterBuilder
+
+	// SetJoinPolicyMap sets the map the join policies named by relations are looked up in.
+	SetJoinPolicyMap(joinPolicyMap policy.IJoinPolicyMap) IFilterBuilder
+
+	// SetUnionPolicy sets the policy used to run the pipeline against other collections as well.
+	SetUnionPolicy(unionPolicy policy.IUnionPolicy) IFilterBuilder
+
+	// SetMergePolicy sets the policy used to merge the duplicate fields that do not name one.
+	SetMergePolicy(mergePolicy policy.IMergePolicy) IFilterBuilder
+
+	// SetMergePolicyMap sets the map the merge policies named by fields are looked up in.
+	SetMergePolicyMap(mergePolicyMap policy.IMergePolicyMap) IFilterBuilder
+
+	// SetSort sets the sort document the results are ordered by, e.g. {postedAt: -1}.
+	SetSort(sort bson.D) IFilterBuilder
+
+	// SetPagination sets the number of documents to skip and the maximum number of documents to return.
+	SetPagination(pagination paginator.Pagination) IFilterBuilder
+
+	// SetCursor sets the values of the sort keys of the last document of the previous page.
+	SetCursor(cursor bson.D) IFilterBuilder
+
+	// SetProjection sets the projection of the fields the query returns.
+	SetProjection(projection projection.IProjection) IFilterBuilder
+
+	// SetAnalyzer sets the analyzer the fields combined into a single condition are checked with.
+	SetAnalyzer(analyzer analyzer.IAnalyzer) IFilterBuilder
+
+	// SetContradictionPolicy sets the policy used to resolve the fields which can never match.
+	SetContradictionPolicy(contradictionPolicy policy.IContradictionPolicy) IFilterBuilder
+
 	// AddFields adds a list of fields to the filter.
 	AddFields(fields []field.IFilterField) IFilterBuilder
 
@@ -33,7 +79,8 @@ type IFilterBuilder interface {
 	// GetFields returns a list of all fields.
 	GetFields() []field.IFilterField
 
-	// GetFieldByIndex returns a field by its index.
+	// GetFieldByIndex returns a field by the stable index the builder assigned to it
+	// when it was added, or nil if there is no such field.
 	GetFieldByIndex(index int) field.IFilterField
 
 	// GetFieldsByName returns a list of fields if their names match the provided one.
@@ -42,7 +89,7 @@ type IFilterBuilder interface {
 	// RemoveField removes a field from the filter.
 	RemoveField(field field.IFilterField) IFilterBuilder
 
-	// RemoveFieldByIndex removes a field from the filter by its index.
+	// RemoveFieldByIndex removes a field from the filter by the stable index the builder assigned to it.
 	RemoveFieldByIndex(index int) IFilterBuilder
 
 	// RemoveFieldByName removes a field from the filter by its name.
@@ -56,66 +103,509 @@ type IFilterBuilder interface {
 
 	// Output returns the final bson.D object
 	Output() bson.D
+
+	// Pipeline returns the final aggregation pipeline
+	// including the join stages for fields from other collections.
+	Pipeline() mongo.Pipeline
+
+	// Sort returns the sort document the results are ordered by
+	Sort() bson.D
+
+	// Pagination returns the number of documents to skip and the maximum number of documents to return
+	Pagination() paginator.Pagination
+
+	// FindOptions returns the options of the find query with the sort, pagination and projection of the filter.
+	FindOptions() *options.FindOptions
+
+	// Err returns the first error that occurred while merging or building the filter, if any.
+	Err() error
 }
 
 // filterBuilder is the default implementation of IFilterBuilder
 // All the attributes are private and can only be accessed via the public methods.
-// The reason for this is to ensure that the filter is immutable and can only be modified
-// via the public methods, while the logic of adding/removing fields is delegated to the
-// IFilterField interface that includes the logic not related to the IFilterBuilder (SRP + DIP).
+// The reason for this is to ensure that the filter is immutable: every method modifying it
+// returns a modified copy (sharing the fields with the original) and leaves the original as it is,
+// so a base filter can be built once and extended concurrently by several goroutines.
+// The logic not related to the IFilterBuilder is delegated to the IFilterField interface (SRP + DIP).
 // (e.g. checking the validity of the field name or its operator,
 // merging fields with each other, etc.)
 type filterBuilder struct {
 	fields             []field.IFilterField
+	indexes            []int
+	nextIndex          int
+	collection         string
+	joinPolicy         policy.IJoinPolicy
+	joinPolicyMap      policy.IJoinPolicyMap
+	unionPolicy        policy.IUnionPolicy
+	mergePolicy        policy.IMergePolicy
+	mergePolicyMap     policy.IMergePolicyMap
+	analyzer           analyzer.IAnalyzer
+	contradiction      policy.IContradictionPolicy
+	sort               bson.D
+	pagination         paginator.Pagination
+	cursor             bson.D
+	projection         projection.IProjection
+	err                error
 	output             bson.D
+	pipeline           mongo.Pipeline
 	input              interface{}
 	modificationNeeded bool
 }
 
 // SetFields sets the list of fields for the filter.
+// The fields are indexed by the builder starting from 0 (see GetFieldByIndex).
 func (f *filterBuilder) SetFields(fields []field.IFilterField) IFilterBuilder {
-	f.fields = fields
-	return f
+	next := f.clone()
+	next.fields, next.indexes, next.nextIndex = nil, nil, 0
+	next.appendFields(fields...)
+	return next
+}
+
+// SetCollection sets the collection the filter is run against.
+// Only the fields with a relation are joined in the pipeline output,
+// the fields of other collections without one are matched as the fields of this collection.
+func (f *filterBuilder) SetCollection(collection string) IFilterBuilder {
+	next := f.clone()
+	next.collection = collection
+	return next
+}
+
+// SetJoinPolicy sets the policy used to join the relations that do not name one.
+func (f *filterBuilder) SetJoinPolicy(joinPolicy policy.IJoinPolicy) IFilterBuilder {
+	next := f.clone()
+	next.joinPolicy = joinPolicy
+	return next
+}
+
+// SetJoinPolicyMap sets the map the join policies named by relations are looked up in.
+func (f *filterBuilder) SetJoinPolicyMap(joinPolicyMap policy.IJoinPolicyMap) IFilterBuilder {
+	next := f.clone()
+	next.joinPolicyMap = joinPolicyMap
+	return next
+}
+
+// SetUnionPolicy sets the policy used to run the pipeline against other collections as well.
+// The pipeline is not combined with any other collection unless the union policy is set.
+func (f *filterBuilder) SetUnionPolicy(unionPolicy policy.IUnionPolicy) IFilterBuilder {
+	next := f.clone()
+	next.unionPolicy = unionPolicy
+	return next
+}
+
+// SetMergePolicy sets the policy used to merge the duplicate fields that do not name one.
+func (f *filterBuilder) SetMergePolicy(mergePolicy policy.IMergePolicy) IFilterBuilder {
+	next := f.clone()
+	next.mergePolicy = mergePolicy
+	return next
+}
+
+// SetMergePolicyMap sets the map the merge policies named by fields are looked up in.
+func (f *filterBuilder) SetMergePolicyMap(mergePolicyMap policy.IMergePolicyMap) IFilterBuilder {
+	next := f.clone()
+	next.mergePolicyMap = mergePolicyMap
+	return next
+}
+
+// SetSort sets the sort document the results are ordered by, e.g. {postedAt: -1}.
+// The pipeline sorts the results after all the other stages.
+func (f *filterBuilder) SetSort(sort bson.D) IFilterBuilder {
+	next := f.clone()
+	next.sort = sort
+	return next
+}
+
+// SetPagination sets the number of documents to skip and the maximum number of documents to return.
+// The pipeline skips and limits the results after sorting them.
+func (f *filterBuilder) SetPagination(pagination paginator.Pagination) IFilterBuilder {
+	next := f.clone()
+	next.pagination = pagination
+	return next
+}
+
+// SetCursor sets the values of the sort keys of the last document of the previous page
+// (e.g. decoded by paginator.DecodeCursor), so that the next page starts right after it
+// without skipping the documents of the previous pages.
+func (f *filterBuilder) SetCursor(cursor bson.D) IFilterBuilder {
+	next := f.clone()
+	next.cursor = cursor
+	return next
+}
+
+// SetProjection sets the projection of the fields the query returns (e.g. scanned from a response struct).
+// The pipeline joins the relations of the projected fields which are not joined by the filter yet
+// after all the other stages, so that only the documents of the page are joined, and projects them.
+func (f *filterBuilder) SetProjection(projection projection.IProjection) IFilterBuilder {
+	next := f.clone()
+	next.projection = projection
+	return next
+}
+
+// SetAnalyzer sets the analyzer the fields combined into a single condition are checked with.
+// No analyzer is set by default, and setting it to nil turns the analysis off.
+func (f *filterBuilder) SetAnalyzer(analyzer analyzer.IAnalyzer) IFilterBuilder {
+	next := f.clone()
+	next.analyzer = analyzer
+	return next
+}
+
+// SetContradictionPolicy sets the policy used to resolve the fields which can never match.
+func (f *filterBuilder) SetContradictionPolicy(contradictionPolicy policy.IContradictionPolicy) IFilterBuilder {
+	next := f.clone()
+	next.contradiction = contradictionPolicy
+	return next
 }
 
 // Build is used to build bson filter for mongodb based on provided struct.
+// It builds both the single bson.D output and the aggregation pipeline.
+// If the cursor is set, the keyset condition of the documents following it in the sort order
+// is matched along with the fields (the sort is tie broken by _id to order all the documents).
 func (f *filterBuilder) Build() IFilterBuilder {
-	// TODO implement me
-	panic("implement me")
+	next := f.clone()
+	fields := next.fields
+	if len(next.cursor) > 0 {
+		next.sort = paginator.KeysetSort(next.sort)
+		fields = next.keysetFields()
+	}
+
+	next.output = next.match(fields)
+	next.pipeline = next.buildPipeline(fields)
+	if next.unionPolicy != nil {
+		next.pipeline = next.unionPolicy.Union(next.pipeline)
+	}
+	if len(next.sort) > 0 {
+		next.pipeline = append(next.pipeline, bson.D{{Key: "$sort", Value: next.sort}})
+	}
+	if next.pagination.Skip > 0 {
+		next.pipeline = append(next.pipeline, bson.D{{Key: "$skip", Value: next.pagination.Skip}})
+	}
+	if next.pagination.Limit > 0 {
+		next.pipeline = append(next.pipeline, bson.D{{Key: "$limit", Value: next.pagination.Limit}})
+	}
+	if next.projection != nil {
+		next.pipeline = next.appendProjection(next.pipeline, fields)
+	}
+	return next
+}
+
+// appendProjection appends the join stages of the relations of the projected fields
+// which are not joined for the fields of the filter already, followed by the $project stage.
+func (f *filterBuilder) appendProjection(pipeline mongo.Pipeline, fields []field.IFilterField) mongo.Pipeline {
+	joins := map[string]bool{}
+	for _, fld := range fields {
+		for relation := fld.GetRelation(); relation != nil; relation = relation.Parent {
+			joins[relation.JoinPath()] = true
+		}
+	}
+
+	for _, relation := range f.projection.Relations() {
+		pipeline = f.appendJoin(pipeline, relation, nil, joins)
+	}
+	return append(pipeline, bson.D{{Key: "$project", Value: f.projection.Pipeline()}})
+}
+
+// keysetFields returns the fields of the builder along with the keyset fields of its cursor.
+// The keyset fields of the sort keys on joined documents carry the relations the builder joins them with,
+// so that they are matched after the joins.
+func (f *filterBuilder) keysetFields() []field.IFilterField {
+	index := 0
+	for _, fld := range f.fields {
+		if fld.GetIndex() >= index {
+			index = fld.GetIndex() + 1
+		}
+	}
+
+	keyset, err := paginator.KeysetFields(f.collection, f.relations(), f.sort, f.cursor, index)
+	if err != nil {
+		f.setErr(err)
+		return f.fields
+	}
+	return append(f.fields, keyset...)
+}
+
+// relations returns the relations the fields and the projection of the builder are joined with,
+// along with the relations they are joined through
+func (f *filterBuilder) relations() []*field.Relation {
+	var relations []*field.Relation
+	for _, fld := range f.fields {
+		for relation := fld.GetRelation(); relation != nil; relation = relation.Parent {
+			relations = append(relations, relation)
+		}
+	}
+
+	if f.projection != nil {
+		for _, projected := range f.projection.Relations() {
+			projected := projected
+			for relation := &projected; relation != nil; relation = relation.Parent {
+				relations = append(relations, relation)
+			}
+		}
+	}
+	return relations
+}
+
+// match combines the outputs of the provided fields into a single bson.D
+// If the outputs share a key (e.g. two unmerged fields with the same name
+// or two merged fields producing $or), they are combined using $and instead,
+// since a query document cannot hold the same key twice.
+func (f *filterBuilder) match(fields []field.IFilterField) bson.D {
+	output := bson.D{}
+	documents := bson.A{}
+	keys := map[string]bool{}
+	duplicate := false
+
+	for _, fld := range f.group(fields, nil) {
+		fieldOutput := fld.Build().Output()
+		for _, element := range fieldOutput {
+			duplicate = duplicate || keys[element.Key]
+			keys[element.Key] = true
+		}
+		output = append(output, fieldOutput...)
+		documents = append(documents, fieldOutput)
+	}
+
+	if duplicate {
+		return bson.D{{Key: "$and", Value: documents}}
+	}
+	return output
+}
+
+// group replaces the fields of every group nested directly in the parent group
+// with a single field combining them by the merge policy named by the group method.
+// The nested groups are combined recursively and take the place of their first field.
+func (f *filterBuilder) group(fields []field.IFilterField, parent *field.Group) []field.IFilterField {
+	members := map[*field.Group][]field.IFilterField{}
+	for _, fld := range fields {
+		if child := childGroup(fld.GetGroup(), parent); child != nil {
+			members[child] = append(members[child], fld)
+		}
+	}
+
+	var items []field.IFilterField
+	grouped := map[*field.Group]bool{}
+	for _, fld := range fields {
+		child := childGroup(fld.GetGroup(), parent)
+		if child == nil {
+			items = append(items, fld)
+			continue
+		}
+		if grouped[child] {
+			continue
+		}
+		grouped[child] = true
+
+		children := f.group(members[child], child)
+		method := child.Method
+		if method == "" {
+			method = "and"
+		}
+		mergePolicy := f.mergePolicyMap.Get(method)
+		if mergePolicy == nil {
+			f.setErr(errors.Errorf("merge policy %s of group %s is not supported", method, child.Name))
+			items = append(items, children...)
+			continue
+		}
+		items = append(items, field.NewMergedField(members[child],
+			mergePolicy.Merge(children...)).SetGroup(parent))
+	}
+	return items
+}
+
+// childGroup returns the group nested directly in the parent group the provided group
+// is (or is nested in), or nil if the provided group is the parent group itself.
+func childGroup(group *field.Group, parent *field.Group) *field.Group {
+	for ; group != nil; group = group.Parent {
+		if group.Parent == parent {
+			return group
+		}
+	}
+	return nil
+}
+
+// rootGroup returns the top-level group the provided group is nested in (or the group itself)
+func rootGroup(group *field.Group) *field.Group {
+	for group != nil && group.Parent != nil {
+		group = group.Parent
+	}
+	return group
+}
+
+// buildPipeline groups the fields by their relation and builds the aggregation pipeline.
+// The fields of the builder's own collection are matched before any join takes place,
+// so that the database can use indexes and join only the documents that are left.
+// The fields of every relation are matched right after its join stages.
+// Groups with fields of several relations (or of a relation and the builder's own collection)
+// cannot be split between the stages and are matched after all the joins instead.
+func (f *filterBuilder) buildPipeline(fields []field.IFilterField) mongo.Pipeline {
+	var local, deferred []field.IFilterField
+	var relations []field.Relation
+	joined := map[string][]field.IFilterField{}
+	spanning := f.spanningGroups(fields)
+
+	for _, fld := range fields {
+		relation := fld.GetRelation()
+		if relation != nil {
+			if _, exists := joined[relation.JoinPath()]; !exists {
+				relations = append(relations, *relation)
+				joined[relation.JoinPath()] = nil
+			}
+		}
+
+		switch {
+		case spanning[rootGroup(fld.GetGroup())]:
+			deferred = append(deferred, fld)
+		case relation == nil:
+			local = append(local, fld)
+		default:
+			joined[relation.JoinPath()] = append(joined[relation.JoinPath()], fld)
+		}
+	}
+
+	pipeline := mongo.Pipeline{}
+
+	// a filter without any joins is still a single $match stage
+	if len(local) > 0 || len(relations) == 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: f.match(local)}})
+	}
+
+	joins := map[string]bool{}
+	for _, relation := range relations {
+		pipeline = f.appendJoin(pipeline, relation, joined, joins)
+	}
+
+	if len(deferred) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: f.match(deferred)}})
+	}
+	return pipeline
+}
+
+// spanningGroups returns the top-level groups whose fields are matched in different stages
+func (f *filterBuilder) spanningGroups(fields []field.IFilterField) map[*field.Group]bool {
+	stages := map[*field.Group]*field.Relation{}
+	spanning := map[*field.Group]bool{}
+	for _, fld := range fields {
+		root := rootGroup(fld.GetGroup())
+		if root == nil {
+			continue
+		}
+		relation := fld.GetRelation()
+		stage, exists := stages[root]
+		if !exists {
+			stages[root] = relation
+			continue
+		}
+		if (stage == nil) != (relation == nil) || (stage != nil && stage.JoinPath() != relation.JoinPath()) {
+			spanning[root] = true
+		}
+	}
+	return spanning
+}
+
+// appendJoin appends the join stages of the relation followed by the $match of its fields.
+// The relations a relation is joined through are appended before it (in dependency order),
+// even if none of their own fields are filtered by. Every join path is joined only once.
+func (f *filterBuilder) appendJoin(pipeline mongo.Pipeline, relation field.Relation,
+	joined map[string][]field.IFilterField, joins map[string]bool) mongo.Pipeline {
+	if joins[relation.JoinPath()] {
+		return pipeline
+	}
+	joins[relation.JoinPath()] = true
+
+	if relation.Parent != nil {
+		pipeline = f.appendJoin(pipeline, *relation.Parent, joined, joins)
+	}
+
+	joinPolicy := f.joinPolicy
+	if relation.Policy != "" {
+		joinPolicy = f.joinPolicyMap.Get(relation.Policy)
+	}
+	if joinPolicy == nil {
+		f.setErr(errors.Errorf("join policy %s is not supported", relation.Policy))
+		return pipeline
+	}
+
+	pipeline = append(pipeline, joinPolicy.Join(&relation)...)
+	if fields := joined[relation.JoinPath()]; len(fields) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: f.match(fields)}})
+	}
+	return pipeline
 }
 
 // AddField adds a new field to the filter.
 func (f *filterBuilder) AddField(field field.IFilterField) IFilterBuilder {
-	f.fields = append(f.fields, field)
-	return f
+	next := f.clone()
+	next.appendFields(field)
+	return next
 }
 
 // AddFields adds a list of fields to the filter.
 func (f *filterBuilder) AddFields(fields []field.IFilterField) IFilterBuilder {
-	f.fields = append(f.fields, fields...)
-	return f
+	next := f.clone()
+	next.appendFields(fields...)
+	return next
+}
+
+// appendFields appends the fields to the fields of the builder (a clone)
+// and assigns them the next indexes of the builder
+func (f *filterBuilder) appendFields(fields ...field.IFilterField) {
+	for _, fld := range fields {
+		f.fields = append(f.fields, fld)
+		f.indexes = append(f.indexes, f.nextIndex)
+		f.nextIndex++
+	}
 }
 
 // RemoveField removes a field from the filter.
-func (f *filterBuilder) RemoveField(field field.IFilterField) IFilterBuilder {
-	f.fields = append(f.fields[:field.GetIndex()], f.fields[field.GetIndex()+1:]...)
-	return f
+// The field is looked up by identity, so removing it does not depend
+// on its index or position and removing it again does nothing.
+func (f *filterBuilder) RemoveField(fld field.IFilterField) IFilterBuilder {
+	return f.removeFields(func(_ int, candidate field.IFilterField) bool {
+		return candidate == fld
+	})
 }
 
-// RemoveFieldByName removes a field from the filter by its name.
+// RemoveFieldByName removes all the fields with the provided name from the filter.
 func (f *filterBuilder) RemoveFieldByName(name string) IFilterBuilder {
-	for idx, field := range f.fields {
-		if field.GetName() == name {
-			f.fields = append(f.fields[:idx], f.fields[idx+1:]...)
-		}
-	}
-	return f
+	return f.removeFields(func(_ int, candidate field.IFilterField) bool {
+		return candidate.GetName() == name
+	})
 }
 
 // RemoveFieldByIndex removes a field from the filter by its index.
+// The index is the stable one the builder assigned to the field (see GetFieldByIndex),
+// not its current position, so it stays valid after other fields are removed.
 func (f *filterBuilder) RemoveFieldByIndex(index int) IFilterBuilder {
-	f.fields = append(f.fields[:index], f.fields[index+1:]...)
-	return f
+	removed := f.position(index)
+	return f.removeFields(func(position int, _ field.IFilterField) bool {
+		return position == removed
+	})
+}
+
+// removeFields returns a copy of the builder without the fields the predicate holds for.
+// The remaining fields are copied into a new slice instead of being shifted in place,
+// so the builder the copy is made of is not affected by the removal.
+func (f *filterBuilder) removeFields(remove func(position int, fld field.IFilterField) bool) IFilterBuilder {
+	fields := make([]field.IFilterField, 0, len(f.fields))
+	indexes := make([]int, 0, len(f.indexes))
+	for i, fld := range f.fields {
+		if !remove(i, fld) {
+			fields = append(fields, fld)
+			indexes = append(indexes, f.indexes[i])
+		}
+	}
+	next := f.clone()
+	next.fields = fields[:len(fields):len(fields)]
+	next.indexes = indexes[:len(indexes):len(indexes)]
+	return next
+}
+
+// position returns the current position of the field with the index or -1 if there is no such field
+func (f *filterBuilder) position(index int) int {
+	for i, fieldIndex := range f.indexes {
+		if fieldIndex == index {
+			return i
+		}
+	}
+	return -1
 }
 
 // GetFieldsByName returns a list of fields if their names match the provided one.
@@ -129,27 +619,147 @@ func (f *filterBuilder) GetFieldsByName(name string) []field.IFilterField {
 	return fields
 }
 
-// GetFieldByIndex returns a field by its index.
+// GetFieldByIndex returns a field by its index or nil if there is no such field.
+// The index is the stable one the builder assigned to the field when it was added
+// (the number of fields added before it since SetFields), not its current position
+// nor the index the field was created with, since the fields of different scans share those.
+// Merged fields have the index of the first of the fields they were merged from.
 func (f *filterBuilder) GetFieldByIndex(index int) field.IFilterField {
-	return f.fields[index]
+	if position := f.position(index); position >= 0 {
+		return f.fields[position]
+	}
+	return nil
 }
 
 // GetFields returns a list of all fields.
+// The list is a copy, so modifying it does not modify the filter.
 func (f *filterBuilder) GetFields() []field.IFilterField {
-	return f.fields
+	return append([]field.IFilterField{}, f.fields...)
 }
 
 // MergeDuplicateFields merges duplicate fields into a single field
+// The fields with the same name are merged by the merge policy their merge method names
+// (e.g. `merge:"or"`), or by the default merge policy if none of them names one.
+// The merged field takes the place of the first of the duplicate fields.
+// Only the fields of the same group are merged, and only if the group combines
+// its fields with and, since the fields of e.g. an or group are alternatives already.
 func (f *filterBuilder) MergeDuplicateFields() IFilterBuilder {
-	for _, field := range f.fields {
-		for _, field2 := range f.fields {
-			if field.GetName() == field2.GetName() && field.GetIndex() != field2.GetIndex() {
-				field.Merge(field2)
-				f.RemoveField(field2)
+	next := f.clone()
+
+	type duplicateKey struct {
+		group *field.Group
+		name  string
+	}
+
+	var keys []duplicateKey
+	duplicates := map[duplicateKey][]field.IFilterField{}
+	duplicateIndexes := map[duplicateKey][]int{}
+	for i, fld := range f.fields {
+		key := duplicateKey{group: fld.GetGroup(), name: fld.GetName()}
+		if _, exists := duplicates[key]; !exists {
+			keys = append(keys, key)
+		}
+		duplicates[key] = append(duplicates[key], fld)
+		duplicateIndexes[key] = append(duplicateIndexes[key], f.indexes[i])
+	}
+
+	fields := make([]field.IFilterField, 0, len(keys))
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		merged := duplicates[key]
+		if key.group == nil || key.group.Method == "" || key.group.Method == "and" {
+			merged = next.merge(duplicates[key])
+		}
+		fields = append(fields, merged...)
+
+		// the merged field takes the index of the first of the duplicate fields
+		if len(merged) < len(duplicates[key]) {
+			indexes = append(indexes, duplicateIndexes[key][0])
+			continue
+		}
+		indexes = append(indexes, duplicateIndexes[key]...)
+	}
+	next.fields, next.indexes = fields, indexes
+	return next
+}
+
+// merge merges the fields with the same name into a single field.
+// If the merge policy could not be found, the fields are left as they are.
+// If the builder has an analyzer, the fields which all have to match (a single field
+// or fields merged by a conjunctive policy) are analyzed first and replaced by the output of the contradiction policy if they can never match.
+func (f *filterBuilder) merge(fields []field.IFilterField) []field.IFilterField {
+	mergePolicy, err := f.getMergePolicy(fields)
+	if err != nil {
+		f.setErr(err)
+		return fields
+	}
+
+	if f.analyzer != nil && (len(fields) == 1 || conjunctive(mergePolicy)) {
+		if contradiction := f.analyzer.Analyze(fields...); contradiction != nil {
+			output, err := f.contradiction.Resolve(contradiction, fields...)
+			if err != nil {
+				f.setErr(err)
+				return fields
 			}
+			return []field.IFilterField{field.NewMergedField(fields, output)}
+		}
+	}
+
+	if len(fields) < 2 {
+		return fields
+	}
+
+	return []field.IFilterField{field.NewMergedField(fields, mergePolicy.Merge(fields...))}
+}
+
+// conjunctive checks whether the output of the merge policy only matches if every merged field does
+func conjunctive(mergePolicy policy.IMergePolicy) bool {
+	conjunctivePolicy, ok := mergePolicy.(policy.IConjunctiveMergePolicy)
+	return ok && conjunctivePolicy.Conjunctive()
+}
+
+// getMergePolicy returns the merge policy named by the merge method of the fields.
+// It returns error if the fields name different merge methods or if the named one is not supported.
+func (f *filterBuilder) getMergePolicy(fields []field.IFilterField) (policy.IMergePolicy, error) {
+	method := ""
+	for _, fld := range fields {
+		if fld.GetMergeMethod() == "" || fld.GetMergeMethod() == method {
+			continue
+		}
+		if method != "" {
+			return nil, errors.Errorf("fields %s have conflicting merge methods %s and %s",
+				fld.GetName(), method, fld.GetMergeMethod())
 		}
+		method = fld.GetMergeMethod()
+	}
+
+	if method == "" {
+		return f.mergePolicy, nil
+	}
+
+	mergePolicy := f.mergePolicyMap.Get(method)
+	if mergePolicy == nil {
+		return nil, errors.Errorf("merge policy %s is not supported", method)
+	}
+	return mergePolicy, nil
+}
+
+// clone returns a shallow copy of the builder every modification is made on,
+// so that the builder itself is never modified. The copy shares the fields and the policies
+// with the builder; the capacity of its fields (and indexes) slice is capped at its length,
+// so that appending to it always allocates a new array instead of writing into the shared one.
+func (f *filterBuilder) clone() *filterBuilder {
+	next := *f
+	next.fields = f.fields[:len(f.fields):len(f.fields)]
+	next.indexes = f.indexes[:len(f.indexes):len(f.indexes)]
+	return &next
+}
+
+// setErr keeps the first error that occurred while merging or building the filter
+func (f *filterBuilder) setErr(err error) {
+	if f.err == nil {
+		f.err = err
 	}
-	return f
 }
 
 // Output returns the final bson.D object
@@ -157,11 +767,63 @@ func (f *filterBuilder) Output() bson.D {
 	return f.output
 }
 
+// Pipeline returns the final aggregation pipeline
+func (f *filterBuilder) Pipeline() mongo.Pipeline {
+	return f.pipeline
+}
+
+// Sort returns the sort document the results are ordered by
+func (f *filterBuilder) Sort() bson.D {
+	return f.sort
+}
+
+// Pagination returns the number of documents to skip and the maximum number of documents to return
+func (f *filterBuilder) Pagination() paginator.Pagination {
+	return f.pagination
+}
+
+// FindOptions returns the options of the find query with the sort, pagination and projection of the filter
+// (the projection of the fields of the own collection only, since the find query joins no relations),
+// so that the Output of the filter can be used with Find the same way as the Pipeline with Aggregate.
+func (f *filterBuilder) FindOptions() *options.FindOptions {
+	findOptions := options.Find()
+	if len(f.sort) > 0 {
+		findOptions.SetSort(f.sort)
+	}
+	if f.pagination.Skip > 0 {
+		findOptions.SetSkip(f.pagination.Skip)
+	}
+	if f.pagination.Limit > 0 {
+		findOptions.SetLimit(f.pagination.Limit)
+	}
+	if f.projection != nil {
+		findOptions.SetProjection(f.projection.Find())
+	}
+	return findOptions
+}
+
+// Err returns the first error that occurred while merging or building the filter, if any.
+func (f *filterBuilder) Err() error {
+	return f.err
+}
+
 // NewFilterBuilder creates a new instance of IFilterBuilder
+// Fields from other collections are joined using the left outer join policy by default,
+// unless their relation names another policy of the default join policy map.
+// Duplicate fields are merged using the coalesce merge policy by default,
+// unless they name another policy of the default merge policy map.
+// The fields are not analyzed for contradictions unless an analyzer is set (see SetAnalyzer).
 func NewFilterBuilder() IFilterBuilder {
 	return &filterBuilder{
 		fields:             []field.IFilterField{},
+		joinPolicy:         policy.NewLeftOuterJoinPolicy(),
+		joinPolicyMap:      policy.NewJoinPolicyMap(),
+		mergePolicy:        policy.NewCoalesceMergePolicy(),
+		mergePolicyMap:     policy.NewMergePolicyMap(),
+		contradiction:      policy.NewErrorContradictionPolicy(),
 		output:             bson.D{},
+		pipeline:           mongo.Pipeline{},
+		sort:               bson.D{},
 		modificationNeeded: false,
 	}
 }
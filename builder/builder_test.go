@@ -1 +1,1109 @@
 package builder_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/analyzer"
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/evaluator"
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/policy"
+	"github.com/jobsearch-demos/mongo-filter-struct/projection"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestFilterBuilder_Pipeline(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		fields     []field.IFilterField
+		want       mongo.Pipeline
+	}{
+		{
+			name: "Fields without relations yield a single $match",
+			fields: []field.IFilterField{
+				field.NewFilterField("", reflect.String.String(),
+					"title", "golang", operator.EQOperator{}, 0),
+				field.NewFilterField("", reflect.Int.String(),
+					"salary", 5000, operator.GTOperator{}, 1),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{
+					{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+					{Key: "salary", Value: bson.D{{Key: "$gt", Value: 5000}}},
+				}}},
+			},
+		},
+		{
+			name:   "No fields yield a single empty $match",
+			fields: []field.IFilterField{},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{}}},
+			},
+		},
+		{
+			name:       "Fields of other collections without a relation are not joined",
+			collection: "jobs",
+			fields: []field.IFilterField{
+				field.NewFilterField("companies", reflect.String.String(),
+					"companyId", "acme", operator.EQOperator{}, 0),
+				field.NewFilterField("jobs", reflect.String.String(),
+					"title", "golang", operator.EQOperator{}, 1),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{
+					{Key: "companyId", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+					{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+				}}},
+			},
+		},
+		{
+			name:       "Fields with the same relation are joined once using its join keys",
+			collection: "jobs",
+			fields: []field.IFilterField{
+				field.NewFilterField("companies", reflect.String.String(),
+					"company.name", "acme", operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+				field.NewFilterField("jobs", reflect.String.String(),
+					"title", "golang", operator.EQOperator{}, 1),
+				field.NewFilterField("companies", reflect.Int.String(),
+					"company.size", 100, operator.GTOperator{}, 2).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{
+					{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+				}}},
+				{{Key: "$lookup", Value: bson.M{
+					"from":         "companies",
+					"localField":   "companyId",
+					"foreignField": "_id",
+					"as":           "company",
+				}}},
+				{{Key: "$unwind", Value: bson.M{
+					"path":                       "$company",
+					"preserveNullAndEmptyArrays": true,
+				}}},
+				{{Key: "$match", Value: bson.D{
+					{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+					{Key: "company.size", Value: bson.D{{Key: "$gt", Value: 100}}},
+				}}},
+			},
+		},
+		{
+			name:       "Relation chains are joined in dependency order",
+			collection: "jobs",
+			fields: []field.IFilterField{
+				field.NewFilterField("industries", reflect.String.String(),
+					"company.industry.name", "it", operator.EQOperator{}, 0).
+					SetRelation(&field.Relation{
+						Collection:   "industries",
+						LocalField:   "company.industryId",
+						ForeignField: "_id",
+						As:           "company.industry",
+						Parent:       field.NewRelation("companies", "companyId", "_id", "company"),
+					}),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$lookup", Value: bson.M{
+					"from":         "companies",
+					"localField":   "companyId",
+					"foreignField": "_id",
+					"as":           "company",
+				}}},
+				{{Key: "$unwind", Value: bson.M{
+					"path":                       "$company",
+					"preserveNullAndEmptyArrays": true,
+				}}},
+				{{Key: "$lookup", Value: bson.M{
+					"from":         "industries",
+					"localField":   "company.industryId",
+					"foreignField": "_id",
+					"as":           "company.industry",
+				}}},
+				{{Key: "$unwind", Value: bson.M{
+					"path":                       "$company.industry",
+					"preserveNullAndEmptyArrays": true,
+				}}},
+				{{Key: "$match", Value: bson.D{
+					{Key: "company.industry.name", Value: bson.D{{Key: "$eq", Value: "it"}}},
+				}}},
+			},
+		},
+		{
+			name:       "Relations naming a join policy are joined using it",
+			collection: "jobs",
+			fields: []field.IFilterField{
+				field.NewFilterField("categories", reflect.String.String(),
+					"categories._id", "backend", operator.EQOperator{}, 0).
+					SetRelation(&field.Relation{
+						Collection:       "categories",
+						LocalField:       "categoryId",
+						ForeignField:     "_id",
+						As:               "categories",
+						Policy:           "graph",
+						ConnectFromField: "parentId",
+					}),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$graphLookup", Value: bson.D{
+					{Key: "from", Value: "categories"},
+					{Key: "startWith", Value: "$categoryId"},
+					{Key: "connectFromField", Value: "parentId"},
+					{Key: "connectToField", Value: "_id"},
+					{Key: "as", Value: "categories"},
+				}}},
+				{{Key: "$match", Value: bson.D{
+					{Key: "categories._id", Value: bson.D{{Key: "$eq", Value: "backend"}}},
+				}}},
+			},
+		},
+		{
+			name:       "Joins are not preceded by an empty $match",
+			collection: "jobs",
+			fields: []field.IFilterField{
+				field.NewFilterField("companies", reflect.String.String(),
+					"company.name", "acme", operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+			},
+			want: mongo.Pipeline{
+				{{Key: "$lookup", Value: bson.M{
+					"from":         "companies",
+					"localField":   "companyId",
+					"foreignField": "_id",
+					"as":           "company",
+				}}},
+				{{Key: "$unwind", Value: bson.M{
+					"path":                       "$company",
+					"preserveNullAndEmptyArrays": true,
+				}}},
+				{{Key: "$match", Value: bson.D{
+					{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := builder.NewFilterBuilder().
+				SetCollection(tt.collection).
+				SetFields(tt.fields).
+				Build().
+				Pipeline()
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilterBuilder_Output(t *testing.T) {
+	got := builder.NewFilterBuilder().
+		AddField(field.NewFilterField("", reflect.String.String(),
+			"title", "golang", operator.RegexOperator{}, 0)).
+		AddField(field.NewFilterField("", reflect.Int.String(),
+			"salary", 5000, operator.LTOperator{}, 1)).
+		Build().
+		Output()
+
+	assert.Equal(t, bson.D{
+		{Key: "title", Value: bson.D{{Key: "$regex", Value: "golang"}}},
+		{Key: "salary", Value: bson.D{{Key: "$lt", Value: 5000}}},
+	}, got)
+}
+
+func TestFilterBuilder_Err(t *testing.T) {
+	relation := field.NewRelation("companies", "companyId", "_id", "company")
+	relation.Policy = "not_supported"
+
+	filterBuilder := builder.NewFilterBuilder().
+		AddField(field.NewFilterField("companies", reflect.String.String(),
+			"company.name", "acme", operator.EQOperator{}, 0).
+			SetRelation(relation)).
+		Build()
+
+	assert.Error(t, filterBuilder.Err())
+}
+
+func TestFilterBuilder_UnionPolicy(t *testing.T) {
+	got := builder.NewFilterBuilder().
+		SetUnionPolicy(policy.NewUnionWithPolicy("archivedJobs")).
+		AddField(field.NewFilterField("", reflect.String.String(),
+			"title", "golang", operator.EQOperator{}, 0)).
+		Build().
+		Pipeline()
+
+	match := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+	}}}
+	assert.Equal(t, mongo.Pipeline{
+		match,
+		{{Key: "$unionWith", Value: bson.D{
+			{Key: "coll", Value: "archivedJobs"},
+			{Key: "pipeline", Value: mongo.Pipeline{match}},
+		}}},
+	}, got)
+}
+
+func TestFilterBuilder_MergeDuplicateFields(t *testing.T) {
+	newAge := func(value int, op operator.IOperator, index int) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "age", value, op, index)
+	}
+	title := field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 1)
+
+	tests := []struct {
+		name    string
+		fields  []field.IFilterField
+		policy  policy.IMergePolicy
+		wantErr bool
+	}{
+		{
+			name:   "Duplicate fields without merge method are merged by the default policy",
+			fields: []field.IFilterField{newAge(18, operator.GTOperator{}, 0), title, newAge(65, operator.LTOperator{}, 2)},
+			policy: policy.NewCoalesceMergePolicy(),
+		},
+		{
+			name: "Duplicate fields are merged by the policy named by their merge method",
+			fields: []field.IFilterField{
+				newAge(18, operator.LTOperator{}, 0),
+				title,
+				newAge(65, operator.GTOperator{}, 2).SetMergeMethod("or"),
+			},
+			policy: policy.NewOrMergePolicy(),
+		},
+		{
+			name: "Duplicate fields with conflicting merge methods are not merged",
+			fields: []field.IFilterField{
+				newAge(18, operator.LTOperator{}, 0).SetMergeMethod("and"),
+				title,
+				newAge(65, operator.GTOperator{}, 2).SetMergeMethod("or"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate fields with unsupported merge method are not merged",
+			fields: []field.IFilterField{
+				newAge(18, operator.LTOperator{}, 0).SetMergeMethod("not_supported"),
+				title,
+				newAge(65, operator.GTOperator{}, 2),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filterBuilder := builder.NewFilterBuilder().
+				SetFields(tt.fields).
+				MergeDuplicateFields()
+
+			if tt.wantErr {
+				assert.Error(t, filterBuilder.Err())
+				assert.ElementsMatch(t, tt.fields, filterBuilder.GetFields())
+				return
+			}
+
+			assert.NoError(t, filterBuilder.Err())
+			got := filterBuilder.GetFields()
+			assert.Len(t, got, 2)
+			assert.Equal(t, "age", got[0].GetName())
+			assert.Equal(t, tt.policy.Merge(tt.fields[0], tt.fields[2]), got[0].Output())
+			assert.Equal(t, title, got[1])
+		})
+	}
+}
+
+func TestFilterBuilder_Output_DuplicateKeys(t *testing.T) {
+	newAge := func(value int, op operator.IOperator, index int) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "age", value, op, index)
+	}
+	newSalary := func(value int, op operator.IOperator, index int) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "salary", value, op, index)
+	}
+
+	got := builder.NewFilterBuilder().
+		SetMergePolicy(policy.NewOrMergePolicy()).
+		SetFields([]field.IFilterField{
+			newAge(18, operator.LTOperator{}, 0),
+			newAge(65, operator.GTOperator{}, 1),
+			newSalary(1000, operator.LTOperator{}, 2),
+			newSalary(5000, operator.GTOperator{}, 3),
+		}).
+		MergeDuplicateFields().
+		Build().
+		Output()
+
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: 18}}}},
+			bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: 65}}}},
+		}}},
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "salary", Value: bson.D{{Key: "$lt", Value: 1000}}}},
+			bson.D{{Key: "salary", Value: bson.D{{Key: "$gt", Value: 5000}}}},
+		}}},
+	}}}, got)
+}
+
+func TestFilterBuilder_Output_Groups(t *testing.T) {
+	location := field.NewGroup("location", "or", nil)
+	office := field.NewGroup("office", "", location)
+
+	tests := []struct {
+		name    string
+		fields  []field.IFilterField
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			name: "Fields of a group are combined by its method",
+			fields: []field.IFilterField{
+				field.NewFilterField("", reflect.String.String(),
+					"title", "golang", operator.EQOperator{}, 0),
+				field.NewFilterField("", reflect.Bool.String(),
+					"remote", true, operator.EQOperator{}, 1).SetGroup(location),
+				field.NewFilterField("", reflect.String.String(),
+					"city", "Baku", operator.EQOperator{}, 2).SetGroup(location),
+			},
+			want: bson.D{
+				{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+					bson.D{{Key: "city", Value: bson.D{{Key: "$eq", Value: "Baku"}}}},
+				}},
+			},
+		},
+		{
+			name: "Nested groups without method are combined with and",
+			fields: []field.IFilterField{
+				field.NewFilterField("", reflect.Bool.String(),
+					"remote", true, operator.EQOperator{}, 0).SetGroup(location),
+				field.NewFilterField("", reflect.String.String(),
+					"city", "Baku", operator.EQOperator{}, 1).SetGroup(office),
+				field.NewFilterField("", reflect.Bool.String(),
+					"relocation", true, operator.EQOperator{}, 2).SetGroup(office),
+			},
+			want: bson.D{
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+					bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "city", Value: bson.D{{Key: "$eq", Value: "Baku"}}}},
+						bson.D{{Key: "relocation", Value: bson.D{{Key: "$eq", Value: true}}}},
+					}}},
+				}},
+			},
+		},
+		{
+			name: "Groups with unsupported method are not combined",
+			fields: []field.IFilterField{
+				field.NewFilterField("", reflect.Bool.String(),
+					"remote", true, operator.EQOperator{}, 0).
+					SetGroup(field.NewGroup("location", "not_supported", nil)),
+			},
+			want: bson.D{
+				{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filterBuilder := builder.NewFilterBuilder().
+				SetFields(tt.fields).
+				Build()
+
+			assert.Equal(t, tt.wantErr, filterBuilder.Err() != nil)
+			assert.Equal(t, tt.want, filterBuilder.Output())
+		})
+	}
+}
+
+func TestFilterBuilder_Pipeline_Groups(t *testing.T) {
+	relation := field.NewRelation("companies", "companyId", "_id", "company")
+	group := field.NewGroup("employer", "or", nil)
+
+	got := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		SetFields([]field.IFilterField{
+			field.NewFilterField("jobs", reflect.String.String(),
+				"title", "golang", operator.EQOperator{}, 0),
+			field.NewFilterField("jobs", reflect.String.String(),
+				"employer", "acme", operator.EQOperator{}, 1).SetGroup(group),
+			field.NewFilterField("companies", reflect.String.String(),
+				"company.name", "acme", operator.EQOperator{}, 2).
+				SetRelation(relation).SetGroup(group),
+		}).
+		Build().
+		Pipeline()
+
+	// the group spans the local collection and the relation,
+	// so it can only be matched after the join
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "employer", Value: bson.D{{Key: "$eq", Value: "acme"}}}},
+				bson.D{{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "acme"}}}},
+			}},
+		}}},
+	}, got)
+}
+
+func TestFilterBuilder_MergeDuplicateFields_Groups(t *testing.T) {
+	or := field.NewGroup("age", "or", nil)
+	fields := []field.IFilterField{
+		field.NewFilterField("", reflect.Int.String(), "age", 18, operator.LTOperator{}, 0).SetGroup(or),
+		field.NewFilterField("", reflect.Int.String(), "age", 65, operator.GTOperator{}, 1).SetGroup(or),
+		field.NewFilterField("", reflect.Int.String(), "age", 0, operator.GTOperator{}, 2),
+	}
+
+	got := builder.NewFilterBuilder().
+		SetFields(fields).
+		MergeDuplicateFields().
+		GetFields()
+
+	// the fields of the or group are alternatives and are not merged
+	// with each other, nor with the field outside of the group
+	assert.Equal(t, fields, got)
+}
+
+func TestFilterBuilder_MergeDuplicateFields_Contradictions(t *testing.T) {
+	fields := []field.IFilterField{
+		field.NewFilterField("", reflect.Int.String(), "age", 10, operator.GTOperator{}, 0),
+		field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 1),
+		field.NewFilterField("", reflect.Int.String(), "age", 5, operator.LTOperator{}, 2),
+	}
+
+	tests := []struct {
+		name     string
+		analyzer analyzer.IAnalyzer
+		policy   policy.IContradictionPolicy
+		want     bson.D
+		wantErr  bool
+	}{
+		{
+			name: "Fields are not analyzed by default",
+			want: bson.D{
+				{Key: "age", Value: bson.D{{Key: "$gt", Value: 10}, {Key: "$lt", Value: 5}}},
+				{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+			},
+		},
+		{
+			name:     "Contradicting fields are reported as an error by default",
+			analyzer: analyzer.NewContradictionAnalyzer(),
+			want: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: 10}}}},
+				bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: 5}}}},
+				bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:     "Contradicting fields are replaced by a condition matching nothing",
+			analyzer: analyzer.NewContradictionAnalyzer(),
+			policy:   policy.NewMatchNothingContradictionPolicy(),
+			want: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{}}}},
+				{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filterBuilder := builder.NewFilterBuilder().SetFields(fields).SetAnalyzer(tt.analyzer)
+			if tt.policy != nil {
+				filterBuilder = filterBuilder.SetContradictionPolicy(tt.policy)
+			}
+			filterBuilder = filterBuilder.MergeDuplicateFields().Build()
+
+			assert.Equal(t, tt.wantErr, filterBuilder.Err() != nil)
+			assert.Equal(t, tt.want, filterBuilder.Output())
+		})
+	}
+}
+
+func TestFilterBuilder_MergeDuplicateFields_DisjunctiveFieldsAreNotAnalyzed(t *testing.T) {
+	filterBuilder := builder.NewFilterBuilder().
+		SetAnalyzer(analyzer.NewContradictionAnalyzer()).
+		SetFields([]field.IFilterField{
+			field.NewFilterField("", reflect.Int.String(), "age", 5, operator.EQOperator{}, 0).SetMergeMethod("or"),
+			field.NewFilterField("", reflect.Int.String(), "age", 6, operator.EQOperator{}, 1),
+		}).
+		MergeDuplicateFields()
+
+	assert.NoError(t, filterBuilder.Err())
+}
+
+func TestFilterBuilder_RemoveField(t *testing.T) {
+	fields := []field.IFilterField{
+		field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+		field.NewFilterField("", reflect.Int.String(), "age", 18, operator.GTOperator{}, 1),
+		field.NewFilterField("", reflect.Int.String(), "age", 65, operator.LTOperator{}, 2),
+		field.NewFilterField("", reflect.Bool.String(), "remote", true, operator.EQOperator{}, 3),
+	}
+
+	filterBuilder := builder.NewFilterBuilder().SetFields(fields)
+	before := filterBuilder.GetFields()
+
+	// the indexes are stable, so they stay valid after other fields are removed
+	filterBuilder = filterBuilder.RemoveField(fields[0]).RemoveField(fields[0]).RemoveFieldByIndex(3)
+	assert.Equal(t, []field.IFilterField{fields[1], fields[2]}, filterBuilder.GetFields())
+	assert.Equal(t, fields[2], filterBuilder.GetFieldByIndex(2))
+	assert.Nil(t, filterBuilder.GetFieldByIndex(3))
+
+	filterBuilder = filterBuilder.RemoveFieldByName("age")
+	assert.Empty(t, filterBuilder.GetFields())
+
+	// neither the provided fields nor the fields returned earlier are modified
+	assert.Equal(t, fields, before)
+}
+
+func TestFilterBuilder_RemoveFieldByIndex_SeveralScans(t *testing.T) {
+	// the fields of different scans (or parsed queries) are created with the same indexes
+	title := field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0)
+	tenant := field.NewFilterField("", reflect.String.String(), "tenant", "acme", operator.EQOperator{}, 0)
+	remote := field.NewFilterField("", reflect.Bool.String(), "remote", true, operator.EQOperator{}, 1)
+
+	filterBuilder := builder.NewFilterBuilder().
+		SetFields([]field.IFilterField{title}).
+		AddFields([]field.IFilterField{tenant, remote})
+	assert.Equal(t, tenant, filterBuilder.GetFieldByIndex(1))
+
+	filterBuilder = filterBuilder.RemoveFieldByIndex(0)
+	assert.Equal(t, []field.IFilterField{tenant, remote}, filterBuilder.GetFields())
+
+	// the same field added twice has two indexes
+	filterBuilder = filterBuilder.AddField(tenant).RemoveFieldByIndex(1)
+	assert.Equal(t, []field.IFilterField{remote, tenant}, filterBuilder.GetFields())
+	assert.Equal(t, tenant, filterBuilder.GetFieldByIndex(3))
+}
+
+// TestFilterBuilder_RandomOperations performs random sequences of add, remove and merge operations
+// and checks the fields of the builder against a plain slice modelling the expected fields.
+// All the fields are created with the same index (as the fields of different scans would be),
+// so the indexes the builder assigns to them have to be unique on their own.
+func TestFilterBuilder_RandomOperations(t *testing.T) {
+	names := []string{"title", "age", "salary"}
+
+	for seed := int64(0); seed < 200; seed++ {
+		random := rand.New(rand.NewSource(seed))
+		filterBuilder := builder.NewFilterBuilder()
+		model := []field.IFilterField{}
+		indexes := []int{}
+		index := 0
+
+		for step := 0; step < 50; step++ {
+			switch operation := random.Intn(6); {
+			case operation == 0 || operation == 1:
+				fld := field.NewFilterField("", reflect.Int.String(),
+					names[random.Intn(len(names))], index, operator.GTOperator{}, 0)
+				filterBuilder = filterBuilder.AddField(fld)
+				model, indexes = append(model, fld), append(indexes, index)
+				index++
+			case operation == 2 && len(model) > 0:
+				fld := model[random.Intn(len(model))]
+				filterBuilder = filterBuilder.RemoveField(fld)
+				model, indexes = removeFields(model, indexes, func(other field.IFilterField, _ int) bool {
+					return other == fld
+				})
+			case operation == 3:
+				removed := random.Intn(index + 1)
+				filterBuilder = filterBuilder.RemoveFieldByIndex(removed)
+				model, indexes = removeFields(model, indexes, func(_ field.IFilterField, other int) bool {
+					return other == removed
+				})
+			case operation == 4:
+				name := names[random.Intn(len(names))]
+				filterBuilder = filterBuilder.RemoveFieldByName(name)
+				model, indexes = removeFields(model, indexes, func(other field.IFilterField, _ int) bool {
+					return other.GetName() == name
+				})
+			case operation == 5:
+				filterBuilder = filterBuilder.MergeDuplicateFields()
+				model, indexes = assertMerged(t, model, indexes, filterBuilder)
+			}
+
+			if !assert.Equal(t, model, filterBuilder.GetFields(), "seed %d step %d", seed, step) {
+				return
+			}
+			for i, fld := range model {
+				assert.Equal(t, fld, filterBuilder.GetFieldByIndex(indexes[i]))
+			}
+		}
+		assert.NoError(t, filterBuilder.Err())
+	}
+}
+
+// removeFields returns the fields (and their indexes) the predicate does not hold for
+func removeFields(fields []field.IFilterField, indexes []int,
+	remove func(field.IFilterField, int) bool) ([]field.IFilterField, []int) {
+	remainingFields, remainingIndexes := []field.IFilterField{}, []int{}
+	for i, fld := range fields {
+		if !remove(fld, indexes[i]) {
+			remainingFields = append(remainingFields, fld)
+			remainingIndexes = append(remainingIndexes, indexes[i])
+		}
+	}
+	return remainingFields, remainingIndexes
+}
+
+// assertMerged checks that the merged fields hold a single field per name of the model,
+// in the order of their first appearance and with the index of the first field with the name.
+func assertMerged(t *testing.T, model []field.IFilterField, indexes []int,
+	filterBuilder builder.IFilterBuilder) ([]field.IFilterField, []int) {
+	var firstIndexes []int
+	var firstNames []string
+	seen := map[string]bool{}
+	for i, fld := range model {
+		if !seen[fld.GetName()] {
+			seen[fld.GetName()] = true
+			firstIndexes = append(firstIndexes, indexes[i])
+			firstNames = append(firstNames, fld.GetName())
+		}
+	}
+
+	merged := filterBuilder.GetFields()
+	assert.Len(t, merged, len(firstNames))
+	for i := range firstNames {
+		if i < len(merged) {
+			assert.Equal(t, firstNames[i], merged[i].GetName())
+			assert.Equal(t, merged[i], filterBuilder.GetFieldByIndex(firstIndexes[i]))
+		}
+	}
+	return merged, firstIndexes
+}
+
+func TestFilterBuilder_Immutable(t *testing.T) {
+	tenant := field.NewFilterField("", reflect.String.String(), "tenant", "acme", operator.EQOperator{}, 0)
+	deleted := field.NewFilterField("", reflect.Bool.String(), "deleted", true, operator.NEOperator{}, 1)
+	title := field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 2)
+
+	base := builder.NewFilterBuilder().SetFields([]field.IFilterField{tenant, deleted})
+	extended := base.AddField(title).RemoveField(deleted).SetCollection("jobs").Build()
+
+	assert.Equal(t, []field.IFilterField{tenant, deleted}, base.GetFields())
+	assert.Equal(t, bson.D{}, base.Output())
+	assert.Equal(t, []field.IFilterField{tenant, title}, extended.GetFields())
+	assert.Equal(t, bson.D{
+		{Key: "tenant", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+		{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+	}, extended.Output())
+}
+
+// TestFilterBuilder_Concurrent extends a shared base filter from several goroutines at once.
+// It is meant to be run with -race.
+func TestFilterBuilder_Concurrent(t *testing.T) {
+	base := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		AddFields([]field.IFilterField{
+			field.NewFilterField("jobs", reflect.String.String(), "tenant", "acme", operator.EQOperator{}, 0),
+			field.NewFilterField("jobs", reflect.Bool.String(), "deleted", true, operator.NEOperator{}, 1),
+		})
+
+	const goroutines = 32
+	outputs := make([]bson.D, goroutines)
+	done := make(chan struct{})
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			outputs[i] = base.
+				AddField(field.NewFilterField("jobs", reflect.Int.String(), "salary", i, operator.GTOperator{}, 2)).
+				AddField(field.NewFilterField("jobs", reflect.Int.String(), "salary", i+1000, operator.LTOperator{}, 3)).
+				MergeDuplicateFields().
+				Build().
+				Output()
+		}(i)
+	}
+	for i := 0; i < goroutines; i++ {
+		<-done
+	}
+
+	for i, output := range outputs {
+		assert.Equal(t, bson.D{
+			{Key: "tenant", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+			{Key: "deleted", Value: bson.D{{Key: "$ne", Value: true}}},
+			{Key: "salary", Value: bson.D{{Key: "$gt", Value: i}, {Key: "$lt", Value: i + 1000}}},
+		}, output)
+	}
+	assert.Len(t, base.GetFields(), 2)
+}
+
+// TestFilterBuilder_Concurrent_AddFieldChain branches builders off a base built by a chain of AddField calls,
+// whose fields slice has spare capacity the branches must not write into. It is meant to be run with -race.
+func TestFilterBuilder_Concurrent_AddFieldChain(t *testing.T) {
+	base := builder.NewFilterBuilder().
+		AddField(field.NewFilterField("jobs", reflect.String.String(), "tenant", "acme", operator.EQOperator{}, 0)).
+		AddField(field.NewFilterField("jobs", reflect.Bool.String(), "deleted", true, operator.NEOperator{}, 1)).
+		AddField(field.NewFilterField("jobs", reflect.Bool.String(), "remote", true, operator.EQOperator{}, 2))
+
+	names := []string{"x", "y"}
+	fields := make([][]field.IFilterField, len(names))
+	done := make(chan struct{})
+	for i, name := range names {
+		go func(i int, name string) {
+			defer func() { done <- struct{}{} }()
+			fields[i] = base.
+				AddField(field.NewFilterField("jobs", reflect.String.String(), name, name, operator.EQOperator{}, 3)).
+				GetFields()
+		}(i, name)
+	}
+	for range names {
+		<-done
+	}
+
+	for i, name := range names {
+		assert.Len(t, fields[i], 4)
+		assert.Equal(t, name, fields[i][3].GetName())
+	}
+	assert.Len(t, base.GetFields(), 3)
+}
+
+func TestFilterBuilder_Sort(t *testing.T) {
+	sort := bson.D{{Key: "postedAt", Value: -1}, {Key: "title", Value: 1}}
+	filterBuilder := builder.NewFilterBuilder().
+		SetFields([]field.IFilterField{
+			field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+		}).
+		SetSort(sort).
+		Build()
+
+	assert.Equal(t, sort, filterBuilder.Sort())
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		}}},
+		{{Key: "$sort", Value: sort}},
+	}, filterBuilder.Pipeline())
+}
+
+func TestFilterBuilder_Pagination(t *testing.T) {
+	sort := bson.D{{Key: "postedAt", Value: -1}}
+	filterBuilder := builder.NewFilterBuilder().
+		SetFields([]field.IFilterField{
+			field.NewFilterField("", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+		}).
+		SetSort(sort).
+		SetPagination(paginator.Pagination{Skip: 20, Limit: 10}).
+		Build()
+
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		}}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$skip", Value: int64(20)}},
+		{{Key: "$limit", Value: int64(10)}},
+	}, filterBuilder.Pipeline())
+
+	assert.Equal(t, options.Find().SetSort(sort).SetSkip(20).SetLimit(10), filterBuilder.FindOptions())
+	assert.Equal(t, options.Find(), builder.NewFilterBuilder().Build().FindOptions())
+}
+
+func TestFilterBuilder_Cursor(t *testing.T) {
+	filterBuilder := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		SetFields([]field.IFilterField{
+			field.NewFilterField("jobs", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+		}).
+		SetSort(bson.D{{Key: "postedAt", Value: -1}}).
+		SetPagination(paginator.Pagination{Limit: 10}).
+		SetCursor(bson.D{{Key: "postedAt", Value: 100}, {Key: "_id", Value: "job1"}})
+
+	built := filterBuilder.Build()
+	sort := bson.D{{Key: "postedAt", Value: -1}, {Key: "_id", Value: 1}}
+	match := bson.D{
+		{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "postedAt", Value: bson.D{{Key: "$lt", Value: 100}}}},
+			bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "postedAt", Value: bson.D{{Key: "$eq", Value: 100}}}},
+				bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: "job1"}}}},
+			}}},
+		}},
+	}
+
+	assert.NoError(t, built.Err())
+	assert.Equal(t, match, built.Output())
+	assert.Equal(t, sort, built.Sort())
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$limit", Value: int64(10)}},
+	}, built.Pipeline())
+
+	// the keyset fields are not added to the fields of the builder, so building it again yields the same output
+	assert.Len(t, built.GetFields(), 1)
+	assert.Equal(t, match, built.Build().Output())
+
+	// the cursor has to hold the values of all the sort keys
+	assert.Error(t, filterBuilder.SetCursor(bson.D{{Key: "postedAt", Value: 100}}).Build().Err())
+}
+
+func TestFilterBuilder_Cursor_JoinedSortKey(t *testing.T) {
+	company := field.NewRelation("companies", "companyId", "_id", "company")
+	built := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		SetFields([]field.IFilterField{
+			field.NewFilterField("jobs", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+			field.NewFilterField("companies", reflect.Int.String(), "company.size", 100, operator.GTEOperator{}, 1).
+				SetRelation(company),
+		}).
+		SetSort(bson.D{{Key: "company.name", Value: 1}}).
+		SetCursor(bson.D{{Key: "company.name", Value: "acme"}, {Key: "_id", Value: "job1"}}).
+		Build()
+
+	// the sort key is on the joined company, so the keyset is matched after the join
+	assert.NoError(t, built.Err())
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "company.size", Value: bson.D{{Key: "$gte", Value: 100}}},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "company.name", Value: bson.D{{Key: "$gt", Value: "acme"}}}},
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "acme"}}}},
+					bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: "job1"}}}},
+				}}},
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "company.name", Value: 1}, {Key: "_id", Value: 1}}}},
+	}, built.Pipeline())
+}
+
+func TestFilterBuilder_Projection(t *testing.T) {
+	company := field.NewRelation("companies", "companyId", "_id", "company")
+	industry := field.NewRelation("industries", "industryId", "_id", "industry")
+	fields := []field.IFilterField{
+		field.NewFilterField("jobs", reflect.String.String(), "title", "golang", operator.EQOperator{}, 0),
+		field.NewFilterField("companies", reflect.String.String(),
+			"company.name", "acme", operator.EQOperator{}, 1).SetRelation(company),
+	}
+	project := projection.NewProjection().
+		Add("title", nil).
+		Add("company.name", company).
+		Add("industry.name", industry)
+
+	filterBuilder := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		SetFields(fields).
+		SetPagination(paginator.Pagination{Limit: 10}).
+		SetProjection(project).
+		Build()
+
+	// the company is joined by the filter already, only the industry is joined for the projection
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "acme"}}},
+		}}},
+		{{Key: "$limit", Value: int64(10)}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "industries",
+			"localField":   "industryId",
+			"foreignField": "_id",
+			"as":           "industry",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$industry",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "title", Value: 1},
+			{Key: "company.name", Value: 1},
+			{Key: "industry.name", Value: 1},
+		}}},
+	}, filterBuilder.Pipeline())
+
+	// the find query joins no relations, so only the fields of the own collection are projected
+	assert.Equal(t, options.Find().SetLimit(10).SetProjection(bson.D{{Key: "title", Value: 1}}),
+		filterBuilder.FindOptions())
+}
+
+func TestFilterBuilder_Projection_RelationChain(t *testing.T) {
+	// the relation chain of the filter and the one of the response struct are scanned separately
+	chain := func() *field.Relation {
+		company := field.NewRelation("companies", "companyId", "_id", "company")
+		industry := field.NewRelation("industries", "company.industryId", "_id", "company.industry")
+		industry.Parent = company
+		return industry
+	}
+	project := projection.NewProjection().
+		Add("title", nil).
+		Add("company.industry.name", chain())
+
+	filterBuilder := builder.NewFilterBuilder().
+		SetCollection("jobs").
+		SetFields([]field.IFilterField{
+			field.NewFilterField("industries", reflect.String.String(),
+				"company.industry.name", "it", operator.EQOperator{}, 0).SetRelation(chain()),
+		}).
+		SetProjection(project).
+		Build()
+
+	// the chain is joined by the filter already, so it is not joined again for the projection
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "industries",
+			"localField":   "company.industryId",
+			"foreignField": "_id",
+			"as":           "company.industry",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company.industry",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "company.industry.name", Value: bson.D{{Key: "$eq", Value: "it"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "title", Value: 1},
+			{Key: "company.industry.name", Value: 1},
+		}}},
+	}, filterBuilder.Pipeline())
+}
+
+func TestFilterBuilder_Output_Evaluated(t *testing.T) {
+	salary := func(value int, op operator.IOperator, index int, mergeMethod string) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "salary", value, op, index).SetMergeMethod(mergeMethod)
+	}
+	location := field.NewGroup("location", "or", nil)
+	keyset, err := paginator.KeysetFields("", nil, bson.D{{Key: "salary", Value: 1}},
+		bson.D{{Key: "salary", Value: 2000}, {Key: "_id", Value: 5}}, 0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		fields    []field.IFilterField
+		documents []bson.D
+		want      []bool
+	}{
+		{
+			name: "Coalesced range",
+			fields: []field.IFilterField{
+				salary(1000, operator.GTEOperator{}, 0, ""),
+				salary(3000, operator.LTOperator{}, 1, ""),
+			},
+			documents: []bson.D{{{Key: "salary", Value: 1000}}, {{Key: "salary", Value: 3000}}, {}},
+			want:      []bool{true, false, false},
+		},
+		{
+			name: "Exactly one of the xor fields matches",
+			fields: []field.IFilterField{
+				salary(1000, operator.GTOperator{}, 0, "xor"),
+				salary(3000, operator.LTOperator{}, 1, "xor"),
+			},
+			documents: []bson.D{{{Key: "salary", Value: 2000}}, {{Key: "salary", Value: 500}}, {{Key: "salary", Value: 4000}}},
+			want:      []bool{false, true, true},
+		},
+		{
+			name: "None of the nor fields matches",
+			fields: []field.IFilterField{
+				salary(1000, operator.EQOperator{}, 0, "nor"),
+				salary(2000, operator.EQOperator{}, 1, "nor"),
+			},
+			documents: []bson.D{{{Key: "salary", Value: 1500}}, {{Key: "salary", Value: 2000}}},
+			want:      []bool{true, false},
+		},
+		{
+			name: "Not all of the not fields match",
+			fields: []field.IFilterField{
+				salary(1000, operator.GTOperator{}, 0, "not"),
+				salary(3000, operator.LTOperator{}, 1, "not"),
+			},
+			documents: []bson.D{{{Key: "salary", Value: 2000}}, {{Key: "salary", Value: 4000}}},
+			want:      []bool{false, true},
+		},
+		{
+			name: "Any field of an or group matches",
+			fields: []field.IFilterField{
+				field.NewFilterField("", reflect.Bool.String(), "remote", true, operator.EQOperator{}, 0).SetGroup(location),
+				field.NewFilterField("", reflect.String.String(), "city", "Baku", operator.EQOperator{}, 1).SetGroup(location),
+			},
+			documents: []bson.D{
+				{{Key: "remote", Value: false}, {Key: "city", Value: "Baku"}},
+				{{Key: "remote", Value: false}, {Key: "city", Value: "Berlin"}},
+			},
+			want: []bool{true, false},
+		},
+		{
+			name:   "Keyset fields match the documents following the cursor",
+			fields: keyset,
+			documents: []bson.D{
+				{{Key: "_id", Value: 6}, {Key: "salary", Value: 2000}},
+				{{Key: "_id", Value: 4}, {Key: "salary", Value: 2000}},
+				{{Key: "_id", Value: 1}, {Key: "salary", Value: 3000}},
+				{{Key: "_id", Value: 9}, {Key: "salary", Value: 1000}},
+			},
+			want: []bool{true, false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := builder.NewFilterBuilder().SetFields(tt.fields).MergeDuplicateFields().Build()
+			assert.NoError(t, filter.Err())
+
+			for i, document := range tt.documents {
+				got, err := evaluator.NewEvaluator().Match(filter.Output(), document)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want[i], got, document)
+			}
+		})
+	}
+}
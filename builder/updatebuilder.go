@@ -0,0 +1,166 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package builder
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IUpdateBuilder is used to build bson update document for mongodb based on provided struct.
+// The fields are scanned by IScanner the same way as the fields of a filter, except that their
+// operators are the update operators named by the update tag (e.g. `update:"set"`) and looked up
+// in operator.NewUpdateOperatorMap. Nil pointer fields are skipped by the scanner,
+// so a struct of pointers only updates the fields which are set (PATCH semantics).
+type IUpdateBuilder interface {
+	// SetFields sets the list of fields for the update.
+	SetFields(fields []field.IFilterField) IUpdateBuilder
+
+	// AddField adds a new field to the update.
+	AddField(field field.IFilterField) IUpdateBuilder
+
+	// GetFields returns a list of all fields.
+	GetFields() []field.IFilterField
+
+	// Build is used to build bson update document for mongodb based on provided struct.
+	Build() IUpdateBuilder
+
+	// Output returns the final bson.D object
+	Output() bson.D
+
+	// Err returns the error that occurred while building the update, if any.
+	Err() error
+}
+
+// updateBuilder is the default implementation of IUpdateBuilder
+// It is immutable the same way as filterBuilder: the methods modifying it return a modified copy.
+type updateBuilder struct {
+	fields []field.IFilterField
+	output bson.D
+	err    error
+}
+
+// SetFields sets the list of fields for the update.
+func (u *updateBuilder) SetFields(fields []field.IFilterField) IUpdateBuilder {
+	next := *u
+	next.fields = append([]field.IFilterField{}, fields...)
+	return &next
+}
+
+// AddField adds a new field to the update.
+func (u *updateBuilder) AddField(field field.IFilterField) IUpdateBuilder {
+	next := *u
+	next.fields = append(u.fields[:len(u.fields):len(u.fields)], field)
+	return &next
+}
+
+// GetFields returns a list of all fields.
+// The list is a copy, so modifying it does not modify the update.
+func (u *updateBuilder) GetFields() []field.IFilterField {
+	return append([]field.IFilterField{}, u.fields...)
+}
+
+// Build is used to build bson update document for mongodb based on provided struct.
+// The fields are grouped by their operators in the order of their first appearance,
+// e.g. {$set: {title: "golang"}, $inc: {views: 1}}. Slices are pushed or added to set
+// element by element (using $each) and pulled element by element (using $in),
+// and the unset fields are only unset if their value is not zero (e.g. true).
+// Nil slices are skipped, so that they are only updated if they are set.
+// It returns error if a field (or its parent or child path) is updated more than once,
+// since mongodb rejects such updates, or if there is nothing to update.
+func (u *updateBuilder) Build() IUpdateBuilder {
+	next := *u
+	next.output, next.err = bson.D{}, nil
+
+	var operators []string
+	updates := map[string]bson.D{}
+	var paths []string
+
+	for _, fld := range u.fields {
+		if fld.GetOperator() == nil {
+			continue
+		}
+
+		name := "$" + fld.GetOperator().ExternalName()
+		value, skipped := updateValue(name, fld.GetValue())
+		if skipped {
+			continue
+		}
+
+		for _, path := range paths {
+			if conflicts(path, fld.GetName()) {
+				next.err = errors.Errorf("field %s conflicts with field %s updated before", fld.GetName(), path)
+				return &next
+			}
+		}
+		paths = append(paths, fld.GetName())
+
+		if _, exists := updates[name]; !exists {
+			operators = append(operators, name)
+		}
+		updates[name] = append(updates[name], bson.E{Key: fld.GetName(), Value: value})
+	}
+
+	if len(operators) == 0 {
+		next.err = errors.Errorf("update has no fields to update")
+		return &next
+	}
+
+	for _, name := range operators {
+		next.output = append(next.output, bson.E{Key: name, Value: updates[name]})
+	}
+	return &next
+}
+
+// updateValue returns the value the field is updated with by the update operator,
+// or true if the field is skipped (nil slices are skipped the same way as nil pointers).
+func updateValue(operator string, value interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	slice := rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+
+	switch {
+	case rv.Kind() == reflect.Slice && rv.IsNil():
+		return nil, true
+	case operator == "$unset":
+		return "", !rv.IsValid() || rv.IsZero()
+	case slice && (operator == "$push" || operator == "$addToSet"):
+		return bson.D{{Key: "$each", Value: value}}, false
+	case slice && operator == "$pull":
+		return bson.D{{Key: "$in", Value: value}}, false
+	}
+	return value, false
+}
+
+// conflicts checks whether the paths are the same or one of them is nested in the other
+func conflicts(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// Output returns the final bson.D object
+func (u *updateBuilder) Output() bson.D {
+	return u.output
+}
+
+// Err returns the error that occurred while building the update, if any.
+func (u *updateBuilder) Err() error {
+	return u.err
+}
+
+// NewUpdateBuilder creates a new instance of IUpdateBuilder
+func NewUpdateBuilder() IUpdateBuilder {
+	return &updateBuilder{
+		fields: []field.IFilterField{},
+		output: bson.D{},
+	}
+}
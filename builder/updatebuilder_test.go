@@ -0,0 +1,115 @@
+package builder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/jobsearch-demos/mongo-filter-struct/validator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type JobUpdate struct {
+	Title     *string    `filter:"title" update:"set"`
+	Salary    *int       `filter:"salary" update:"max"`
+	Views     *int       `filter:"views" update:"inc"`
+	Tags      []string   `filter:"tags" update:"addToSet"`
+	Removed   []string   `filter:"tags.removed" update:"pull"`
+	Archived  *bool      `filter:"archivedAt" update:"unset"`
+	UpdatedAt *time.Time `filter:"updatedAt" update:"set"`
+}
+
+type InvalidJobUpdate struct {
+	Title *string `filter:"title" update:"inc"`
+}
+
+func TestUpdateBuilder_Build(t *testing.T) {
+	title := "golang"
+	views := 1
+	archived := true
+	notArchived := false
+	updatedAt := time.Date(2022, 10, 29, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		update  interface{}
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			name:   "Only the set fields are updated",
+			update: JobUpdate{Title: &title, Views: &views},
+			want: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "title", Value: "golang"}}},
+				{Key: "$inc", Value: bson.D{{Key: "views", Value: 1}}},
+			},
+		},
+		{
+			name:   "Timestamps are set as a single value",
+			update: JobUpdate{Title: &title, UpdatedAt: &updatedAt},
+			want: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "title", Value: "golang"}, {Key: "updatedAt", Value: updatedAt}}},
+			},
+		},
+		{
+			name:   "Slices are added to set element by element",
+			update: JobUpdate{Tags: []string{"go", "mongo"}, Archived: &archived},
+			want: bson.D{
+				{Key: "$addToSet", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: []string{"go", "mongo"}}}}}},
+				{Key: "$unset", Value: bson.D{{Key: "archivedAt", Value: ""}}},
+			},
+		},
+		{
+			name:    "Zero unset fields are not unset",
+			update:  JobUpdate{Archived: &notArchived},
+			want:    bson.D{},
+			wantErr: true,
+		},
+		{
+			name:    "Nested paths of the same field conflict",
+			update:  JobUpdate{Tags: []string{"go"}, Removed: []string{"java"}},
+			want:    bson.D{},
+			wantErr: true,
+		},
+		{
+			name:    "Struct without set fields has nothing to update",
+			update:  JobUpdate{},
+			want:    bson.D{},
+			wantErr: true,
+		},
+	}
+
+	updateOperatorMap := operator.NewUpdateOperatorMap()
+	scan := scanner.NewScanner(updateOperatorMap,
+		[]validator.IValidator{validator.NewOperatorValidator(updateOperatorMap, "update")},
+		"filter", "update", "relation")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := scan.Scan(tt.update, nil, 0)
+			assert.NoError(t, err)
+
+			got := builder.NewUpdateBuilder().SetFields(fields).Build()
+			assert.Equal(t, tt.wantErr, got.Err() != nil, got.Err())
+			assert.Equal(t, tt.want, got.Output())
+		})
+	}
+
+	// the update operators are validated against the field types the same way as the filter operators
+	_, err := scan.Scan(InvalidJobUpdate{Title: &title}, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestUpdateBuilder_Immutable(t *testing.T) {
+	title := field.NewFilterField("", "string", "title", "golang", operator.SetOperator{}, 0)
+	base := builder.NewUpdateBuilder().SetFields([]field.IFilterField{title})
+	extended := base.AddField(field.NewFilterField("", "int", "views", 1, operator.IncOperator{}, 1)).Build()
+
+	assert.Len(t, base.GetFields(), 1)
+	assert.Len(t, extended.GetFields(), 2)
+	assert.Equal(t, bson.D{}, base.Output())
+}
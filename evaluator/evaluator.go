@@ -0,0 +1,485 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package evaluator
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IEvaluator is used to check whether a document matches a filter without mongodb,
+// e.g. to unit test the filter structs or as an oracle for the output of the builder.
+type IEvaluator interface {
+	// Match reports whether the document (a bson document or a struct) matches the filter
+	Match(filter bson.D, document interface{}) (bool, error)
+
+	// Compare compares the values in the sort order of mongodb (-1, 0 or 1)
+	Compare(a interface{}, b interface{}) int
+}
+
+// evaluator is the default implementation of IEvaluator.
+// It supports the operators of operator.NewOperatorMap ($eq, $ne, $gt, $gte, $lt, $lte,
+// $in, $nin and $regex along with its $options) and the logical operators $and, $or, $nor and $not.
+// The fields are looked up by dotted paths and follow the array semantics of mongodb:
+// a condition on an array matches if it matches the array itself or any of its elements,
+// and the paths descend into the documents of arrays and into their elements by index.
+// The values are compared the way mongodb compares them: numbers of any type with each other,
+// while the range operators never match the values of different types (e.g. a string and a number).
+type evaluator struct{}
+
+// Match reports whether the document (a bson document or a struct) matches the filter.
+// Both of them are marshaled to bson first, so that the values of the filter
+// and the document are compared the same way as by mongodb (e.g. time.Time as a date).
+func (e *evaluator) Match(filter bson.D, document interface{}) (bool, error) {
+	normalizedFilter, err := normalize(filter)
+	if err != nil {
+		return false, errors.Wrap(err, "filter can not be evaluated")
+	}
+	normalizedDocument, err := normalize(document)
+	if err != nil {
+		return false, errors.Wrap(err, "document can not be evaluated")
+	}
+	return e.match(normalizedFilter, normalizedDocument)
+}
+
+// Compare compares the values in the sort order of mongodb (-1, 0 or 1).
+// The values of different types are ordered by their types (null, numbers, strings,
+// documents, arrays, object ids, booleans, dates), the documents and arrays element by element.
+// The values are expected to be of the types the driver decodes (e.g. int32, bson.A, primitive.DateTime).
+func (e *evaluator) Compare(a interface{}, b interface{}) int {
+	if rankA, rankB := rank(a), rank(b); rankA != rankB {
+		if rankA < rankB {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case bson.D:
+		b := b.(bson.D)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if result := strings.Compare(a[i].Key, b[i].Key); result != 0 {
+				return result
+			}
+			if result := e.Compare(a[i].Value, b[i].Value); result != 0 {
+				return result
+			}
+		}
+		return compareLength(len(a), len(b))
+	case bson.A:
+		b := b.(bson.A)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if result := e.Compare(a[i], b[i]); result != 0 {
+				return result
+			}
+		}
+		return compareLength(len(a), len(b))
+	}
+
+	result, _ := compare(a, b)
+	return result
+}
+
+// rank returns the position of the type of the value in the sort order of mongodb
+func rank(value interface{}) int {
+	if _, ok := number(value); ok {
+		return 1
+	}
+
+	switch value.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 0
+	case string:
+		return 2
+	case bson.D:
+		return 3
+	case bson.A:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	}
+	return 8
+}
+
+// compareLength compares the lengths of the documents or arrays with equal common elements
+func compareLength(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// normalize marshals the value to bson and unmarshals it back,
+// so that the values are of the types the driver decodes (e.g. int32, bson.A, primitive.DateTime)
+func normalize(value interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	document := bson.D{}
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// match reports whether the document matches all the conditions of the filter
+func (e *evaluator) match(filter bson.D, document bson.D) (bool, error) {
+	for _, condition := range filter {
+		var matched bool
+		var err error
+
+		switch condition.Key {
+		case "$and", "$or", "$nor":
+			matched, err = e.logical(condition.Key, condition.Value, document)
+		default:
+			if strings.HasPrefix(condition.Key, "$") {
+				return false, errors.Errorf("operator %s is not supported", condition.Key)
+			}
+			matched, err = e.field(lookup(document, strings.Split(condition.Key, ".")), condition.Value)
+		}
+
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// logical evaluates the $and, $or and $nor operators
+func (e *evaluator) logical(operator string, value interface{}, document bson.D) (bool, error) {
+	filters, ok := value.(bson.A)
+	if !ok || len(filters) == 0 {
+		return false, errors.Errorf("operator %s requires a non empty array", operator)
+	}
+
+	for _, filter := range filters {
+		filterDocument, ok := filter.(bson.D)
+		if !ok {
+			return false, errors.Errorf("operator %s requires an array of documents", operator)
+		}
+
+		matched, err := e.match(filterDocument, document)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case operator == "$and" && !matched:
+			return false, nil
+		case operator == "$or" && matched:
+			return true, nil
+		case operator == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// field evaluates the condition of a field: either a document of operators or a value it has to equal
+func (e *evaluator) field(values []interface{}, condition interface{}) (bool, error) {
+	operators, ok := condition.(bson.D)
+	if !ok || len(operators) == 0 || !strings.HasPrefix(operators[0].Key, "$") {
+		return e.operator("$eq", condition, values, nil)
+	}
+
+	for _, op := range operators {
+		if op.Key == "$options" {
+			continue
+		}
+
+		matched, err := e.operator(op.Key, op.Value, values, operators)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// operator evaluates a single operator against the values found at the path of the field
+// (there are none if the field is missing). The sibling operators hold the $options of $regex.
+func (e *evaluator) operator(operator string, value interface{}, values []interface{}, siblings bson.D) (bool, error) {
+	switch operator {
+	case "$eq":
+		return anyOf(values, value == nil, func(candidate interface{}) bool {
+			return equal(candidate, value)
+		}), nil
+	case "$ne":
+		matched, err := e.operator("$eq", value, values, siblings)
+		return !matched, err
+	case "$gt", "$gte", "$lt", "$lte":
+		return anyOf(values, false, func(candidate interface{}) bool {
+			result, comparable := compare(candidate, value)
+			return comparable && satisfies(operator, result)
+		}), nil
+	case "$in":
+		list, ok := value.(bson.A)
+		if !ok {
+			return false, errors.Errorf("operator %s requires an array", operator)
+		}
+		for _, element := range list {
+			var matched bool
+			var err error
+			if _, regex := element.(primitive.Regex); regex {
+				matched, err = e.operator("$regex", element, values, nil)
+			} else {
+				matched, err = e.operator("$eq", element, values, nil)
+			}
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	case "$nin":
+		matched, err := e.operator("$in", value, values, siblings)
+		return !matched, err
+	case "$regex":
+		expression, err := compileRegex(value, siblings)
+		if err != nil {
+			return false, err
+		}
+		return anyOf(values, false, func(candidate interface{}) bool {
+			text, ok := candidate.(string)
+			return ok && expression.MatchString(text)
+		}), nil
+	case "$not":
+		if _, regex := value.(primitive.Regex); regex {
+			matched, err := e.operator("$regex", value, values, nil)
+			return !matched, err
+		}
+		operators, ok := value.(bson.D)
+		if !ok {
+			return false, errors.Errorf("operator %s requires a document of operators or a regex", operator)
+		}
+		matched, err := e.field(values, operators)
+		return !matched, err
+	}
+	return false, errors.Errorf("operator %s is not supported", operator)
+}
+
+// lookup returns the values found at the path of the document.
+// The path descends into the elements of an array by index (e.g. tags.0)
+// and into all the documents of an array otherwise (e.g. tags.name).
+func lookup(value interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{value}
+	}
+
+	switch value := value.(type) {
+	case bson.D:
+		for _, element := range value {
+			if element.Key == path[0] {
+				return lookup(element.Value, path[1:])
+			}
+		}
+	case bson.A:
+		var values []interface{}
+		if index, err := strconv.Atoi(path[0]); err == nil && index >= 0 && index < len(value) {
+			values = append(values, lookup(value[index], path[1:])...)
+		}
+		for _, element := range value {
+			if document, ok := element.(bson.D); ok {
+				values = append(values, lookup(document, path)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// anyOf reports whether the predicate matches any of the values or any of the elements of the array ones.
+// The missing value is reported if there are no values (i.e. the field is missing).
+func anyOf(values []interface{}, missing bool, predicate func(candidate interface{}) bool) bool {
+	if len(values) == 0 {
+		return missing
+	}
+
+	for _, value := range values {
+		if predicate(value) {
+			return true
+		}
+		if array, ok := value.(bson.A); ok {
+			for _, element := range array {
+				if predicate(element) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// equal checks whether the values are equal the way mongodb compares them
+// (the numbers by value regardless of their type, the documents and arrays element by element)
+func equal(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case bson.D:
+		b, ok := b.(bson.D)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i].Key != b[i].Key || !equal(a[i].Value, b[i].Value) {
+				return false
+			}
+		}
+		return true
+	case bson.A:
+		b, ok := b.(bson.A)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	}
+
+	result, comparable := compare(a, b)
+	return comparable && result == 0
+}
+
+// compare compares the values of the same type (the numbers of any type with each other)
+// and reports whether they are comparable
+func compare(a interface{}, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		}
+	case primitive.DateTime:
+		if b, ok := b.(primitive.DateTime); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case primitive.ObjectID:
+		if b, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(a[:], b[:]), true
+		}
+	}
+	return 0, false
+}
+
+// number converts the numeric value to float64
+func number(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// satisfies checks whether the result of the comparison satisfies the range operator
+func satisfies(operator string, result int) bool {
+	switch operator {
+	case "$gt":
+		return result > 0
+	case "$gte":
+		return result >= 0
+	case "$lt":
+		return result < 0
+	}
+	return result <= 0
+}
+
+// compileRegex compiles the pattern of the $regex operator (a string or a regex)
+// along with its options (either its own or the ones of the sibling $options operator)
+func compileRegex(value interface{}, siblings bson.D) (*regexp.Regexp, error) {
+	var pattern, options string
+	switch value := value.(type) {
+	case string:
+		pattern = value
+	case primitive.Regex:
+		pattern, options = value.Pattern, value.Options
+	default:
+		return nil, errors.Errorf("operator $regex requires a string or a regex")
+	}
+
+	for _, sibling := range siblings {
+		if sibling.Key == "$options" {
+			if siblingOptions, ok := sibling.Value.(string); ok {
+				options = siblingOptions
+			}
+		}
+	}
+
+	// the options of mongodb are the flags of go (besides x which is not supported)
+	var flags string
+	for _, option := range options {
+		if strings.ContainsRune("ims", option) {
+			flags += string(option)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "operator $regex has an invalid pattern")
+	}
+	return expression, nil
+}
+
+// NewEvaluator creates a new evaluator
+func NewEvaluator() IEvaluator {
+	return &evaluator{}
+}
@@ -0,0 +1,247 @@
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Company struct {
+	Name string `bson:"name"`
+}
+
+type Job struct {
+	Title    string    `bson:"title"`
+	Salary   int       `bson:"salary"`
+	Remote   bool      `bson:"remote"`
+	Tags     []string  `bson:"tags"`
+	Company  Company   `bson:"company"`
+	Offices  []Company `bson:"offices"`
+	PostedAt time.Time `bson:"postedAt"`
+}
+
+func TestEvaluator_Match(t *testing.T) {
+	posted := time.Date(2022, 10, 29, 0, 0, 0, 0, time.UTC)
+	job := Job{
+		Title:    "Golang Developer",
+		Salary:   2000,
+		Remote:   true,
+		Tags:     []string{"go", "mongo"},
+		Company:  Company{Name: "acme"},
+		Offices:  []Company{{Name: "berlin"}, {Name: "baku"}},
+		PostedAt: posted,
+	}
+
+	tests := []struct {
+		name     string
+		filter   bson.D
+		document interface{}
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name:     "Empty filter matches everything",
+			filter:   bson.D{},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Eq matches numbers of different types",
+			filter:   bson.D{{Key: "salary", Value: bson.D{{Key: "$eq", Value: int64(2000)}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Implicit eq",
+			filter:   bson.D{{Key: "title", Value: "Golang Developer"}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Ne",
+			filter:   bson.D{{Key: "remote", Value: bson.D{{Key: "$ne", Value: true}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name: "Range operators are combined",
+			filter: bson.D{{Key: "salary", Value: bson.D{
+				{Key: "$gte", Value: 1000}, {Key: "$lt", Value: 2000.5}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Range operators never match values of different types",
+			filter:   bson.D{{Key: "title", Value: bson.D{{Key: "$gt", Value: 1}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Dates are compared",
+			filter:   bson.D{{Key: "postedAt", Value: bson.D{{Key: "$gt", Value: posted.Add(-time.Hour)}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "In",
+			filter:   bson.D{{Key: "company.name", Value: bson.D{{Key: "$in", Value: []string{"acme", "globex"}}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Nin",
+			filter:   bson.D{{Key: "company.name", Value: bson.D{{Key: "$nin", Value: []string{"acme"}}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Empty in matches nothing",
+			filter:   bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{}}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Regex with options",
+			filter:   bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "^golang"}, {Key: "$options", Value: "i"}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Regex is case sensitive by default",
+			filter:   bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "^golang"}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Condition on an array matches any element",
+			filter:   bson.D{{Key: "tags", Value: bson.D{{Key: "$eq", Value: "mongo"}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Condition on an array matches the whole array",
+			filter:   bson.D{{Key: "tags", Value: bson.A{"go", "mongo"}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Ne on an array requires no element to be equal",
+			filter:   bson.D{{Key: "tags", Value: bson.D{{Key: "$ne", Value: "go"}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Path descends into the documents of an array",
+			filter:   bson.D{{Key: "offices.name", Value: "baku"}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Path descends into an array element by index",
+			filter:   bson.D{{Key: "offices.0.name", Value: "baku"}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Missing field equals null and is not equal to values",
+			filter:   bson.D{{Key: "deletedAt", Value: nil}, {Key: "city", Value: bson.D{{Key: "$ne", Value: "berlin"}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Missing field does not match ranges",
+			filter:   bson.D{{Key: "views", Value: bson.D{{Key: "$gte", Value: 0}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name: "Or",
+			filter: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "remote", Value: false}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$gt", Value: 1000}}}},
+			}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name: "And",
+			filter: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "remote", Value: true}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$gt", Value: 3000}}}},
+			}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Nor",
+			filter:   bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "remote", Value: false}}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Not",
+			filter:   bson.D{{Key: "salary", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 1000}}}}}},
+			document: job,
+			want:     false,
+		},
+		{
+			name:     "Not regex",
+			filter:   bson.D{{Key: "title", Value: bson.D{{Key: "$not", Value: primitive.Regex{Pattern: "python"}}}}},
+			document: job,
+			want:     true,
+		},
+		{
+			name:     "Bson document",
+			filter:   bson.D{{Key: "title", Value: "golang"}},
+			document: bson.M{"title": "golang"},
+			want:     true,
+		},
+		{
+			name:     "Unsupported operator",
+			filter:   bson.D{{Key: "title", Value: bson.D{{Key: "$exists", Value: true}}}},
+			document: job,
+			wantErr:  true,
+		},
+		{
+			name:     "Logical operator requires an array",
+			filter:   bson.D{{Key: "$or", Value: bson.D{{Key: "remote", Value: true}}}},
+			document: job,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEvaluator().Match(tt.filter, tt.document)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluator_Compare(t *testing.T) {
+	tests := []struct {
+		name string
+		a    interface{}
+		b    interface{}
+		want int
+	}{
+		{name: "Numbers of different types", a: int32(2), b: 1.5, want: 1},
+		{name: "Equal numbers", a: int64(2), b: int32(2), want: 0},
+		{name: "Strings", a: "a", b: "b", want: -1},
+		{name: "Null before numbers", a: nil, b: int32(0), want: -1},
+		{name: "Numbers before strings", a: "0", b: int32(1), want: 1},
+		{name: "Documents key by key", a: bson.D{{Key: "a", Value: int32(1)}}, b: bson.D{{Key: "a", Value: int32(2)}}, want: -1},
+		{name: "Shorter array first", a: bson.A{int32(1), int32(2)}, b: bson.A{int32(1)}, want: 1},
+		{name: "Booleans", a: false, b: true, want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewEvaluator().Compare(tt.a, tt.b))
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package extjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// identifier matches the keys the shell syntax does not need to quote
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// Marshal renders the filter (e.g. the output of the builder) as canonical or relaxed Extended JSON.
+// The keys of maps (e.g. the $lookup stages of the join policies) are sorted,
+// so that the same filter is always rendered the same way.
+func Marshal(filter bson.D, canonical bool) (string, error) {
+	output, err := bson.MarshalExtJSON(stable(filter), canonical, false)
+	if err != nil {
+		return "", errors.Wrap(err, "filter can not be rendered as extended json")
+	}
+	return string(output), nil
+}
+
+// MarshalPipeline renders the pipeline (e.g. the pipeline of the builder) as an array
+// of canonical or relaxed Extended JSON stages.
+func MarshalPipeline(pipeline mongo.Pipeline, canonical bool) (string, error) {
+	stages := make([]string, 0, len(pipeline))
+	for _, stage := range pipeline {
+		output, err := Marshal(stage, canonical)
+		if err != nil {
+			return "", err
+		}
+		stages = append(stages, output)
+	}
+	return "[" + strings.Join(stages, ",") + "]", nil
+}
+
+// Unmarshal parses the filter from canonical or relaxed Extended JSON (e.g. stored in a config file)
+func Unmarshal(data string) (bson.D, error) {
+	var filter bson.D
+	if err := bson.UnmarshalExtJSON([]byte(data), false, &filter); err != nil {
+		return nil, errors.Wrap(err, "filter is not valid extended json")
+	}
+	return filter, nil
+}
+
+// UnmarshalPipeline parses the pipeline from an array of canonical or relaxed Extended JSON stages
+func UnmarshalPipeline(data string) (mongo.Pipeline, error) {
+	var wrapper struct {
+		Pipeline mongo.Pipeline `bson:"pipeline"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"pipeline":`+data+`}`), false, &wrapper); err != nil {
+		return nil, errors.Wrap(err, "pipeline is not valid extended json")
+	}
+	return wrapper.Pipeline, nil
+}
+
+// Shell renders the filter or the pipeline (or any other value) in the syntax of the mongo shell,
+// e.g. {title: {$regex: "golang"}, postedAt: {$gte: ISODate("2022-10-29T00:00:00.000Z")}},
+// which can be pasted into mongosh (or the legacy mongo shell) as it is.
+func Shell(value interface{}) (string, error) {
+	raw, err := bson.Marshal(bson.D{{Key: "value", Value: stable(value)}})
+	if err != nil {
+		return "", errors.Wrap(err, "value can not be rendered as shell syntax")
+	}
+
+	var wrapper bson.D
+	if err := bson.Unmarshal(raw, &wrapper); err != nil {
+		return "", errors.Wrap(err, "value can not be rendered as shell syntax")
+	}
+
+	var buffer bytes.Buffer
+	if err := shell(&buffer, wrapper[0].Value); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// shell writes the value (of a type the driver decodes) in the syntax of the mongo shell
+func shell(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case bson.D:
+		buffer.WriteString("{")
+		for i, element := range value {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			if identifier.MatchString(element.Key) {
+				buffer.WriteString(element.Key)
+			} else {
+				buffer.WriteString(quote(element.Key))
+			}
+			buffer.WriteString(": ")
+			if err := shell(buffer, element.Value); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("}")
+	case bson.A:
+		buffer.WriteString("[")
+		for i, element := range value {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			if err := shell(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("]")
+	case string:
+		buffer.WriteString(quote(value))
+	case int32:
+		buffer.WriteString(strconv.FormatInt(int64(value), 10))
+	case int64:
+		buffer.WriteString("NumberLong(" + strconv.FormatInt(value, 10) + ")")
+	case float64:
+		buffer.WriteString(float(value))
+	case bool:
+		buffer.WriteString(strconv.FormatBool(value))
+	case nil, primitive.Null:
+		buffer.WriteString("null")
+	case primitive.Undefined:
+		buffer.WriteString("undefined")
+	case primitive.DateTime:
+		buffer.WriteString(`ISODate("` + value.Time().UTC().Format("2006-01-02T15:04:05.000Z07:00") + `")`)
+	case primitive.ObjectID:
+		buffer.WriteString(`ObjectId("` + value.Hex() + `")`)
+	case primitive.Regex:
+		buffer.WriteString("/" + strings.ReplaceAll(value.Pattern, "/", `\/`) + "/" + value.Options)
+	case primitive.Decimal128:
+		buffer.WriteString(`NumberDecimal("` + value.String() + `")`)
+	case primitive.Timestamp:
+		buffer.WriteString("Timestamp(" + strconv.FormatUint(uint64(value.T), 10) + ", " +
+			strconv.FormatUint(uint64(value.I), 10) + ")")
+	case primitive.Binary:
+		buffer.WriteString("BinData(" + strconv.Itoa(int(value.Subtype)) + `, "` +
+			base64.StdEncoding.EncodeToString(value.Data) + `")`)
+	case primitive.MinKey:
+		buffer.WriteString("MinKey()")
+	case primitive.MaxKey:
+		buffer.WriteString("MaxKey()")
+	default:
+		return errors.Errorf("value of type %T can not be rendered as shell syntax", value)
+	}
+	return nil
+}
+
+// quote quotes the string the way javascript does (without escaping html)
+func quote(text string) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	// strings are always encoded
+	_ = encoder.Encode(text)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// float formats the double the way javascript does
+func float(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// stable converts the maps of the value to documents with sorted keys
+// (the other values, including the documents, keep their order)
+func stable(value interface{}) interface{} {
+	switch value := value.(type) {
+	case bson.M:
+		return sorted(value)
+	case map[string]interface{}:
+		return sorted(value)
+	case bson.D:
+		document := make(bson.D, 0, len(value))
+		for _, element := range value {
+			document = append(document, bson.E{Key: element.Key, Value: stable(element.Value)})
+		}
+		return document
+	case bson.A:
+		array := make(bson.A, 0, len(value))
+		for _, element := range value {
+			array = append(array, stable(element))
+		}
+		return array
+	case []interface{}:
+		return stable(bson.A(value))
+	case mongo.Pipeline:
+		array := make(bson.A, 0, len(value))
+		for _, stage := range value {
+			array = append(array, stable(stage))
+		}
+		return array
+	}
+	return value
+}
+
+// sorted converts the map to a document with sorted keys
+func sorted(value map[string]interface{}) bson.D {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	document := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		document = append(document, bson.E{Key: key, Value: stable(value[key])})
+	}
+	return document
+}
@@ -0,0 +1,153 @@
+package extjson
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var posted = time.Date(2022, 10, 29, 12, 30, 0, 0, time.UTC)
+
+var filter = bson.D{
+	{Key: "title", Value: bson.D{{Key: "$regex", Value: "golang"}}},
+	{Key: "salary", Value: bson.D{{Key: "$gte", Value: 1000}}},
+	{Key: "postedAt", Value: bson.D{{Key: "$lt", Value: posted}}},
+	{Key: "tags", Value: bson.D{{Key: "$in", Value: []string{"go", "mongo"}}}},
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    bson.D
+		canonical bool
+		want      string
+	}{
+		{
+			name:      "Relaxed",
+			filter:    filter,
+			canonical: false,
+			want: `{"title":{"$regex":"golang"},"salary":{"$gte":1000},` +
+				`"postedAt":{"$lt":{"$date":"2022-10-29T12:30:00Z"}},"tags":{"$in":["go","mongo"]}}`,
+		},
+		{
+			name:      "Canonical",
+			filter:    bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: 1000}}}},
+			canonical: true,
+			want:      `{"salary":{"$gte":{"$numberInt":"1000"}}}`,
+		},
+		{
+			name:      "Map keys are sorted",
+			filter:    bson.D{{Key: "$lookup", Value: bson.M{"from": "companies", "as": "company", "localField": "companyId"}}},
+			canonical: false,
+			want:      `{"$lookup":{"as":"company","from":"companies","localField":"companyId"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.filter, tt.canonical)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, canonical := range []bool{true, false} {
+		output, err := Marshal(filter, canonical)
+		assert.NoError(t, err)
+
+		got, err := Unmarshal(output)
+		assert.NoError(t, err)
+
+		again, err := Marshal(got, canonical)
+		assert.NoError(t, err)
+		assert.Equal(t, output, again)
+	}
+
+	_, err := Unmarshal(`{"title": `)
+	assert.Error(t, err)
+}
+
+func TestMarshalPipeline(t *testing.T) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "title", Value: "golang"}}}},
+		{{Key: "$limit", Value: int64(10)}},
+	}
+
+	relaxed, err := MarshalPipeline(pipeline, false)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"$match":{"title":"golang"}},{"$limit":10}]`, relaxed)
+
+	// the canonical extended json keeps the types of the numbers
+	got, err := MarshalPipeline(pipeline, true)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"$match":{"title":"golang"}},{"$limit":{"$numberLong":"10"}}]`, got)
+
+	parsed, err := UnmarshalPipeline(got)
+	assert.NoError(t, err)
+	assert.Equal(t, pipeline, parsed)
+
+	_, err = UnmarshalPipeline(`{"$match": {}}`)
+	assert.Error(t, err)
+}
+
+func TestShell(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("635cf5b8b2d7c1a1b2c3d4e5")
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Filter",
+			value: filter,
+			want: `{title: {$regex: "golang"}, salary: {$gte: 1000}, ` +
+				`postedAt: {$lt: ISODate("2022-10-29T12:30:00.000Z")}, tags: {$in: ["go", "mongo"]}}`,
+		},
+		{
+			name: "Pipeline",
+			value: mongo.Pipeline{
+				{{Key: "$lookup", Value: bson.M{"from": "companies", "localField": "companyId", "foreignField": "_id", "as": "company"}}},
+				{{Key: "$match", Value: bson.D{{Key: "company.name", Value: "Acme"}}}},
+				{{Key: "$limit", Value: int64(10)}},
+			},
+			want: `[{$lookup: {as: "company", foreignField: "_id", from: "companies", localField: "companyId"}}, ` +
+				`{$match: {"company.name": "Acme"}}, {$limit: NumberLong(10)}]`,
+		},
+		{
+			name: "Special values",
+			value: bson.D{
+				{Key: "_id", Value: id},
+				{Key: "pattern", Value: primitive.Regex{Pattern: "a/b", Options: "i"}},
+				{Key: "score", Value: 1.5},
+				{Key: "nan", Value: math.NaN()},
+				{Key: "deletedAt", Value: nil},
+				{Key: "remote", Value: true},
+				{Key: "quote", Value: `say "<hi>"`},
+			},
+			want: `{_id: ObjectId("635cf5b8b2d7c1a1b2c3d4e5"), pattern: /a\/b/i, score: 1.5, nan: NaN, ` +
+				`deletedAt: null, remote: true, quote: "say \"<hi>\""}`,
+		},
+		{
+			name:    "Values that are not bson can not be rendered",
+			value:   make(chan int),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Shell(tt.value)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -16,15 +16,21 @@ import (
 
 // IFilterField is used to build bson filter for mongodb based on provided struct.
 // Its main responsibility is to construct a proper bson.D from a provided single struct field.
-// In case of field being duplicated, it merges them into a single bson.D object.
+// In case of field being duplicated, the fields are merged by the merge policy
+// named by their merge method into a single field (see NewMergedField).
 type IFilterField interface {
-	Merge(field IFilterField) IFilterField
 	GetIndex() int
 	GetName() string
 	GetCollection() string
 	GetType() string
 	GetOperator() operator.IOperator
 	GetValue() interface{}
+	GetRelation() *Relation
+	SetRelation(relation *Relation) IFilterField
+	GetMergeMethod() string
+	SetMergeMethod(method string) IFilterField
+	GetGroup() *Group
+	SetGroup(group *Group) IFilterField
 	Build() IFilterField
 	Output() bson.D
 }
@@ -36,7 +42,9 @@ type filterField struct {
 	value      interface{}
 	operator   operator.IOperator
 	index      int
-	output     bson.D
+	relation   *Relation
+	merge      string
+	group      *Group
 }
 
 func (f *filterField) GetName() string {
@@ -63,20 +71,61 @@ func (f *filterField) GetIndex() int {
 	return f.index
 }
 
-// Merge merges two filter fields into a single one
-func (f *filterField) Merge(field IFilterField) IFilterField {
-	panic("implement me")
+// GetRelation returns the relation the field is joined with,
+// or nil if the field is in the collection the filter is run against.
+func (f *filterField) GetRelation() *Relation {
+	return f.relation
+}
+
+// SetRelation sets the relation the field is joined with
+func (f *filterField) SetRelation(relation *Relation) IFilterField {
+	f.relation = relation
+	return f
+}
+
+// GetMergeMethod returns the name of the merge policy used
+// to merge the field with the other fields of the same name.
+func (f *filterField) GetMergeMethod() string {
+	return f.merge
+}
+
+// SetMergeMethod sets the name of the merge policy used
+// to merge the field with the other fields of the same name.
+func (f *filterField) SetMergeMethod(method string) IFilterField {
+	f.merge = method
+	return f
+}
+
+// GetGroup returns the logical group the field belongs to,
+// or nil if it is combined with the rest of the filter directly.
+func (f *filterField) GetGroup() *Group {
+	return f.group
+}
+
+// SetGroup sets the logical group the field belongs to
+func (f *filterField) SetGroup(group *Group) IFilterField {
+	f.group = group
+	return f
 }
 
 // Build builds a bson.D from a single filter field
+// e.g. a field named `age` with the `gte` operator and value 18
+// is built into {age: {$gte: 18}}
+// The output only depends on the name, operator and value of the field, which never change,
+// so it is derived by Output and building does not modify the field. This way the same field
+// can be shared by several builders built concurrently.
 func (f *filterField) Build() IFilterField {
-	//TODO implement me
-	panic("implement me")
+	return f
 }
 
 // Output returns the output of the filter field
 func (f *filterField) Output() bson.D {
-	return f.output
+	return bson.D{
+		{
+			Key:   f.name,
+			Value: bson.D{{Key: "$" + f.operator.ExternalName(), Value: f.value}},
+		},
+	}
 }
 
 // NewFilterField creates a new filter field
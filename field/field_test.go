@@ -1 +1,183 @@
 package field_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilterField_Build(t *testing.T) {
+	tests := []struct {
+		name string
+		fld  field.IFilterField
+		want bson.D
+	}{
+		{
+			name: "Build field with eq operator",
+			fld: field.NewFilterField("", reflect.String.String(),
+				"title", "golang", operator.EQOperator{}, 0),
+			want: bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}},
+		},
+		{
+			name: "Build field with gte operator",
+			fld: field.NewFilterField("", reflect.Int.String(),
+				"salary", 5000, operator.GTEOperator{}, 0),
+			want: bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: 5000}}}},
+		},
+		{
+			name: "Build field with in operator",
+			fld: field.NewFilterField("", reflect.Slice.String(),
+				"tags", []string{"go", "mongo"}, operator.INOperator{}, 0),
+			want: bson.D{{Key: "tags", Value: bson.D{{Key: "$in", Value: []string{"go", "mongo"}}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.fld.Build().Output())
+		})
+	}
+}
+
+func TestParseRelation(t *testing.T) {
+	maxDepth, directOnly := 3, 0
+	tests := []struct {
+		name    string
+		tag     string
+		want    *field.Relation
+		wantErr bool
+	}{
+		{
+			name: "Parse relation with collection only",
+			tag:  "companies",
+			want: field.NewRelation("companies", "", "", ""),
+		},
+		{
+			name: "Parse relation with join keys",
+			tag:  "companies,local=companyId,foreign=_id,as=company",
+			want: field.NewRelation("companies", "companyId", "_id", "company"),
+		},
+		{
+			name: "Parse relation with spaces around options",
+			tag:  "companies, local = companyId, as = company",
+			want: field.NewRelation("companies", "companyId", "", "company"),
+		},
+		{
+			name: "Parse relation with graph options",
+			tag: `categories,policy=graph,local=categoryId,foreign=_id,connectFrom=parentId,` +
+				`maxDepth=3,depthField=depth,restrict={"active": true, "deleted": false}`,
+			want: &field.Relation{
+				Collection:       "categories",
+				LocalField:       "categoryId",
+				ForeignField:     "_id",
+				Policy:           "graph",
+				ConnectFromField: "parentId",
+				MaxDepth:         &maxDepth,
+				DepthField:       "depth",
+				Restrict:         `{"active": true, "deleted": false}`,
+			},
+		},
+		{
+			name: "Parse relation with zero max depth",
+			tag:  "categories,policy=graph,connectFrom=parentId,maxDepth=0",
+			want: &field.Relation{
+				Collection:       "categories",
+				Policy:           "graph",
+				ConnectFromField: "parentId",
+				MaxDepth:         &directOnly,
+			},
+		},
+		{
+			name:    "Parse relation with graph policy without connectFrom",
+			tag:     "categories,policy=graph,local=categoryId,foreign=_id",
+			wantErr: true,
+		},
+		{
+			name:    "Parse relation with invalid max depth",
+			tag:     "categories,maxDepth=-1",
+			wantErr: true,
+		},
+		{
+			name:    "Parse relation with invalid restrict document",
+			tag:     "categories,restrict={active}",
+			wantErr: true,
+		},
+		{
+			name:    "Parse relation without collection",
+			tag:     ",local=companyId",
+			wantErr: true,
+		},
+		{
+			name:    "Parse relation with option without value",
+			tag:     "companies,local",
+			wantErr: true,
+		},
+		{
+			name:    "Parse relation with unsupported option",
+			tag:     "companies,on=companyId",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := field.ParseRelation(tt.tag)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRelation_JoinPath(t *testing.T) {
+	company := field.NewRelation("companies", "companyId", "_id", "company")
+	industry := field.NewRelation("industries", "company.industryId", "_id", "company.industry")
+	industry.Parent = company
+
+	assert.Equal(t, "company", company.JoinPath())
+	assert.Equal(t, "company > company.industry", industry.JoinPath())
+}
+
+func TestParseGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		want    *field.Group
+		wantErr bool
+	}{
+		{
+			name: "Parse group with name only",
+			tag:  "location",
+			want: field.NewGroup("location", "", nil),
+		},
+		{
+			name: "Parse group with method",
+			tag:  "location, or",
+			want: field.NewGroup("location", "or", nil),
+		},
+		{
+			name:    "Parse group without name",
+			tag:     ",or",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := field.ParseGroup(tt.tag)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
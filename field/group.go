@@ -0,0 +1,50 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package field
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Group describes a logical group of filter fields, e.g. `group:"location,or"`
+// groups the fields tagged with it into a single {$or: [...]} block.
+// The fields of a group are combined by the merge policy named by its Method
+// (the and merge policy if empty) and the group itself is combined with the rest
+// of the fields of its Parent (or with the rest of the filter if it has no Parent).
+type Group struct {
+	Name   string
+	Method string
+	Parent *Group
+}
+
+// ParseGroup parses the value of a group tag.
+// The first element is the name of the group, the second one is the optional
+// name of the merge policy combining its fields. If it is missing, the method is left
+// empty so that it can be named by another field of the group (and is used otherwise).
+func ParseGroup(tag string) (*Group, error) {
+	name, method, _ := strings.Cut(tag, ",")
+	name, method = strings.TrimSpace(name), strings.TrimSpace(method)
+
+	if name == "" {
+		return nil, errors.Errorf("group %s has no name", tag)
+	}
+	return &Group{Name: name, Method: method}, nil
+}
+
+// NewGroup creates a new group
+func NewGroup(name string, method string, parent *Group) *Group {
+	return &Group{
+		Name:   name,
+		Method: method,
+		Parent: parent,
+	}
+}
@@ -0,0 +1,105 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package field
+
+import (
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mergedField is the result of merging several fields with the same name.
+// It takes the name, collection, relation, merge method and group of the first merged field,
+// while its output is the one produced by the merge policy.
+type mergedField struct {
+	fields   []IFilterField
+	relation *Relation
+	merge    string
+	group    *Group
+	output   bson.D
+}
+
+func (f *mergedField) GetName() string {
+	return f.fields[0].GetName()
+}
+
+func (f *mergedField) GetCollection() string {
+	return f.fields[0].GetCollection()
+}
+
+func (f *mergedField) GetType() string {
+	return f.fields[0].GetType()
+}
+
+// GetOperator returns nil since the merged fields may use different operators.
+func (f *mergedField) GetOperator() operator.IOperator {
+	return nil
+}
+
+// GetValue returns the values of the merged fields.
+func (f *mergedField) GetValue() interface{} {
+	values := make([]interface{}, 0, len(f.fields))
+	for _, fld := range f.fields {
+		values = append(values, fld.GetValue())
+	}
+	return values
+}
+
+func (f *mergedField) GetIndex() int {
+	return f.fields[0].GetIndex()
+}
+
+func (f *mergedField) GetRelation() *Relation {
+	return f.relation
+}
+
+func (f *mergedField) SetRelation(relation *Relation) IFilterField {
+	f.relation = relation
+	return f
+}
+
+func (f *mergedField) GetMergeMethod() string {
+	return f.merge
+}
+
+func (f *mergedField) SetMergeMethod(method string) IFilterField {
+	f.merge = method
+	return f
+}
+
+func (f *mergedField) GetGroup() *Group {
+	return f.group
+}
+
+func (f *mergedField) SetGroup(group *Group) IFilterField {
+	f.group = group
+	return f
+}
+
+// Build does nothing since the output is built by the merge policy
+func (f *mergedField) Build() IFilterField {
+	return f
+}
+
+// Output returns the output of the merge policy
+func (f *mergedField) Output() bson.D {
+	return f.output
+}
+
+// NewMergedField creates a new field from the fields merged into the provided output.
+// The fields have to share the same name and there has to be at least one of them.
+func NewMergedField(fields []IFilterField, output bson.D) IFilterField {
+	return &mergedField{
+		fields:   fields,
+		relation: fields[0].GetRelation(),
+		merge:    fields[0].GetMergeMethod(),
+		group:    fields[0].GetGroup(),
+		output:   output,
+	}
+}
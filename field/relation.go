@@ -0,0 +1,151 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package field
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Relation describes how the collection of a filter field
+// is joined to the collection the filter is run against.
+// It is parsed from the relation tag, e.g.
+// `relation:"companies,local=companyId,foreign=_id,as=company"`
+// joins the `companies` collection on companies._id == companyId
+// and stores the joined document under the `company` key.
+//
+// Relations declared inside of a joined struct are joined through their Parent
+// (e.g. job -> company -> industry), in which case the local field and the `as` key
+// are dotted paths prefixed with the `as` key of the parent (e.g. company.industryId).
+//
+// The relation is joined by the join policy named by Policy
+// (the default join policy of the builder if empty).
+// The rest of the attributes are only used by the graph join policy
+// which recursively joins the documents connected via ConnectFromField.
+type Relation struct {
+	Collection   string
+	LocalField   string
+	ForeignField string
+	As           string
+	Parent       *Relation
+
+	// Policy is the name of the join policy used to join the relation.
+	Policy string
+	// ConnectFromField is the field the graph join policy recursively joins from.
+	ConnectFromField string
+	// MaxDepth limits the recursion depth of the graph join policy (nil means unlimited,
+	// 0 joins the directly matching documents only).
+	MaxDepth *int
+	// DepthField is the field the recursion depth of every joined document is stored at.
+	DepthField string
+	// Restrict is an Extended JSON document every recursively joined document has to match.
+	Restrict string
+}
+
+// ParseRelation parses the value of a relation tag.
+// The first element is the collection name, the rest are optional
+// key=value pairs (local, foreign, as, policy, connectFrom, maxDepth, depthField, restrict).
+// Missing keys are left empty so that the caller can fill them with its own defaults.
+// The graph policy requires the connectFrom option, since it has nothing to recursively join from without it.
+func ParseRelation(tag string) (*Relation, error) {
+	parts := splitRelation(tag)
+
+	relation := &Relation{Collection: strings.TrimSpace(parts[0])}
+	if relation.Collection == "" {
+		return nil, errors.Errorf("relation %s has no collection", tag)
+	}
+
+	for _, part := range parts[1:] {
+		key, value, found := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || value == "" {
+			return nil, errors.Errorf("relation option %s has no value", part)
+		}
+
+		switch key {
+		case "local":
+			relation.LocalField = value
+		case "foreign":
+			relation.ForeignField = value
+		case "as":
+			relation.As = value
+		case "policy":
+			relation.Policy = value
+		case "connectFrom":
+			relation.ConnectFromField = value
+		case "maxDepth":
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 0 {
+				return nil, errors.Errorf("relation option maxDepth %s is not a non-negative number", value)
+			}
+			relation.MaxDepth = &depth
+		case "depthField":
+			relation.DepthField = value
+		case "restrict":
+			var restrict bson.D
+			if err := bson.UnmarshalExtJSON([]byte(value), false, &restrict); err != nil {
+				return nil, errors.Wrapf(err, "relation option restrict %s is not a valid document", value)
+			}
+			relation.Restrict = value
+		default:
+			return nil, errors.Errorf("relation option %s is not supported", key)
+		}
+	}
+
+	if relation.Policy == "graph" && relation.ConnectFromField == "" {
+		return nil, errors.Errorf("relation %s with the graph policy has no connectFrom option", tag)
+	}
+	return relation, nil
+}
+
+// JoinPath returns the path the relation is joined at: the `as` keys of the relations
+// it is joined through followed by its own (e.g. company > company.industry).
+// Relations with the same join path are joined once, even if they are scanned separately
+// (e.g. from a filter struct and a response struct) and so have different parents.
+func (r *Relation) JoinPath() string {
+	if r.Parent == nil {
+		return r.As
+	}
+	return r.Parent.JoinPath() + " > " + r.As
+}
+
+// splitRelation splits the relation tag value by commas
+// which are not inside of the braces or brackets of a document option.
+func splitRelation(tag string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, char := range tag {
+		switch char {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// NewRelation creates a new relation
+func NewRelation(collection string, localField string, foreignField string, as string) *Relation {
+	return &Relation{
+		Collection:   collection,
+		LocalField:   localField,
+		ForeignField: foreignField,
+		As:           as,
+	}
+}
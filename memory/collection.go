@@ -0,0 +1,713 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/evaluator"
+	"github.com/jobsearch-demos/mongo-filter-struct/repository"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IDatabase holds collections of documents in memory, so that the filters and pipelines
+// built by the builder can be run without mongodb (e.g. in the tests of a service).
+// Its collections implement repository.ICollection the same way as *mongo.Collection.
+type IDatabase interface {
+	// Insert inserts the documents (bson documents or structs) into the collection
+	Insert(collection string, documents ...interface{}) error
+
+	// Collection returns the collection with the provided name (it is empty if nothing is inserted into it)
+	Collection(name string) repository.ICollection
+}
+
+// database is the default implementation of IDatabase.
+// The documents are evaluated by the IEvaluator it is created with.
+type database struct {
+	mutex       sync.RWMutex
+	collections map[string][]bson.D
+	evaluator   evaluator.IEvaluator
+}
+
+// Insert inserts the documents (bson documents or structs) into the collection.
+// The documents are stored as bson documents, so modifying the inserted structs does not modify them.
+func (d *database) Insert(collection string, documents ...interface{}) error {
+	normalized := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		raw, err := bson.Marshal(document)
+		if err != nil {
+			return errors.Wrap(err, "document can not be inserted")
+		}
+
+		var normalizedDocument bson.D
+		if err := bson.Unmarshal(raw, &normalizedDocument); err != nil {
+			return errors.Wrap(err, "document can not be inserted")
+		}
+		normalized = append(normalized, normalizedDocument)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.collections[collection] = append(d.collections[collection], normalized...)
+	return nil
+}
+
+// Collection returns the collection with the provided name (it is empty if nothing is inserted into it)
+func (d *database) Collection(name string) repository.ICollection {
+	return &collection{
+		database: d,
+		name:     name,
+	}
+}
+
+// documents returns the documents of the collection
+func (d *database) documents(name string) []bson.D {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return append([]bson.D{}, d.collections[name]...)
+}
+
+// collection is a collection of the database implementing repository.ICollection.
+// It supports the queries and the pipeline stages the builder emits: the filters of the evaluator,
+// sort, skip, limit and projection, and the $match, $lookup, $unwind, $graphLookup, $unionWith,
+// $sort, $skip, $limit and $project stages (in their basic forms emitted by the policies).
+type collection struct {
+	database *database
+	name     string
+}
+
+// Find returns the documents matching the filter (sorted, skipped, limited and projected by the options)
+func (c *collection) Find(ctx context.Context, filter interface{},
+	opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	findOptions := options.MergeFindOptions(opts...)
+
+	documents, err := c.find(filter, findOptions.Sort, findOptions.Skip, findOptions.Limit, findOptions.Projection)
+	if err != nil {
+		return nil, err
+	}
+	return mongo.NewCursorFromDocuments(values(documents), nil, nil)
+}
+
+// FindOne returns the first document matching the filter (sorted, skipped and projected by the options)
+func (c *collection) FindOne(ctx context.Context, filter interface{},
+	opts ...*options.FindOneOptions) *mongo.SingleResult {
+	var sort, projection interface{}
+	var skip *int64
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			sort = opt.Sort
+		}
+		if opt.Skip != nil {
+			skip = opt.Skip
+		}
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+	}
+
+	limit := int64(1)
+	documents, err := c.find(filter, sort, skip, &limit, projection)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	if len(documents) == 0 {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+	return mongo.NewSingleResultFromDocument(documents[0], nil, nil)
+}
+
+// CountDocuments returns the number of the documents matching the filter
+func (c *collection) CountDocuments(ctx context.Context, filter interface{},
+	opts ...*options.CountOptions) (int64, error) {
+	countOptions := options.MergeCountOptions(opts...)
+
+	documents, err := c.find(filter, nil, countOptions.Skip, countOptions.Limit, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(documents)), nil
+}
+
+// Distinct returns the distinct values of the field of the documents matching the filter
+// (the elements of the array values are distinct values of their own)
+func (c *collection) Distinct(ctx context.Context, fieldName string, filter interface{},
+	opts ...*options.DistinctOptions) ([]interface{}, error) {
+	documents, err := c.find(filter, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	distinct := []interface{}{}
+	add := func(value interface{}) {
+		for _, existing := range distinct {
+			if c.database.evaluator.Compare(existing, value) == 0 {
+				return
+			}
+		}
+		distinct = append(distinct, value)
+	}
+
+	for _, document := range documents {
+		value, exists := get(document, fieldName)
+		if !exists {
+			continue
+		}
+		if array, ok := value.(bson.A); ok {
+			for _, element := range array {
+				add(element)
+			}
+			continue
+		}
+		add(value)
+	}
+	return distinct, nil
+}
+
+// Aggregate returns the documents returned by the pipeline run against the documents of the collection
+func (c *collection) Aggregate(ctx context.Context, pipeline interface{},
+	opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	stages, err := normalizePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := c.aggregate(c.database.documents(c.name), stages)
+	if err != nil {
+		return nil, err
+	}
+	return mongo.NewCursorFromDocuments(values(documents), nil, nil)
+}
+
+// find returns the documents matching the filter sorted, skipped, limited and projected
+func (c *collection) find(filter interface{}, sortSpec interface{}, skip *int64,
+	limit *int64, projection interface{}) ([]bson.D, error) {
+	filterDocument, err := normalize(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "filter is not valid")
+	}
+
+	documents, err := c.match(c.database.documents(c.name), filterDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	if sortSpec != nil {
+		sortDocument, err := normalize(sortSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "sort is not valid")
+		}
+		c.sort(documents, sortDocument)
+	}
+
+	if skip != nil {
+		documents = skipDocuments(documents, *skip)
+	}
+
+	if limit != nil {
+		documents = limitDocuments(documents, *limit)
+	}
+
+	if projection != nil {
+		projectionDocument, err := normalize(projection)
+		if err != nil {
+			return nil, errors.Wrap(err, "projection is not valid")
+		}
+		return project(documents, projectionDocument), nil
+	}
+	return documents, nil
+}
+
+// aggregate runs the stages of the pipeline against the documents
+func (c *collection) aggregate(documents []bson.D, stages []bson.D) ([]bson.D, error) {
+	for _, stage := range stages {
+		if len(stage) != 1 {
+			return nil, errors.Errorf("stage %v must have exactly one key", stage)
+		}
+
+		var err error
+		switch stage[0].Key {
+		case "$match":
+			var filter bson.D
+			if filter, err = document(stage[0]); err == nil {
+				documents, err = c.match(documents, filter)
+			}
+		case "$lookup":
+			var lookup bson.D
+			if lookup, err = document(stage[0]); err == nil {
+				documents, err = c.lookup(documents, lookup)
+			}
+		case "$graphLookup":
+			var graphLookup bson.D
+			if graphLookup, err = document(stage[0]); err == nil {
+				documents, err = c.graphLookup(documents, graphLookup)
+			}
+		case "$unwind":
+			documents, err = unwind(documents, stage[0].Value)
+		case "$unionWith":
+			var unionWith bson.D
+			if unionWith, err = document(stage[0]); err == nil {
+				documents, err = c.unionWith(documents, unionWith)
+			}
+		case "$sort":
+			var sortDocument bson.D
+			if sortDocument, err = document(stage[0]); err == nil {
+				c.sort(documents, sortDocument)
+			}
+		case "$skip":
+			documents = skipDocuments(documents, integer(stage[0].Value))
+		case "$limit":
+			documents = limitDocuments(documents, integer(stage[0].Value))
+		case "$project":
+			var projection bson.D
+			if projection, err = document(stage[0]); err == nil {
+				documents = project(documents, projection)
+			}
+		default:
+			err = errors.Errorf("stage %s is not supported", stage[0].Key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+// match returns the documents matching the filter
+func (c *collection) match(documents []bson.D, filter bson.D) ([]bson.D, error) {
+	matched := []bson.D{}
+	for _, document := range documents {
+		ok, err := c.database.evaluator.Match(filter, document)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, document)
+		}
+	}
+	return matched, nil
+}
+
+// lookup joins the documents of the `from` collection whose foreign field equals
+// the local field of the document (or any of its elements) as an array under the `as` key
+func (c *collection) lookup(documents []bson.D, lookup bson.D) ([]bson.D, error) {
+	from, localField := stringOf(lookup, "from"), stringOf(lookup, "localField")
+	foreignField, as := stringOf(lookup, "foreignField"), stringOf(lookup, "as")
+	if from == "" || localField == "" || foreignField == "" || as == "" {
+		return nil, errors.Errorf("$lookup requires from, localField, foreignField and as")
+	}
+
+	foreign := c.database.documents(from)
+	joined := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		local, _ := get(document, localField)
+		filter := bson.D{{Key: foreignField, Value: bson.D{{Key: "$eq", Value: local}}}}
+		if array, ok := local.(bson.A); ok {
+			filter = bson.D{{Key: foreignField, Value: bson.D{{Key: "$in", Value: array}}}}
+		}
+
+		matched, err := c.match(foreign, filter)
+		if err != nil {
+			return nil, err
+		}
+		joined = append(joined, set(document, as, values(matched)))
+	}
+	return joined, nil
+}
+
+// graphLookup joins the documents of the `from` collection connected to the document recursively
+// (starting with the documents whose connectToField equals startWith and following their connectFromField)
+// as an array under the `as` key
+func (c *collection) graphLookup(documents []bson.D, graphLookup bson.D) ([]bson.D, error) {
+	from, startWith := stringOf(graphLookup, "from"), stringOf(graphLookup, "startWith")
+	connectFrom, connectTo := stringOf(graphLookup, "connectFromField"), stringOf(graphLookup, "connectToField")
+	as, depthField := stringOf(graphLookup, "as"), stringOf(graphLookup, "depthField")
+	if from == "" || !strings.HasPrefix(startWith, "$") || connectFrom == "" || connectTo == "" || as == "" {
+		return nil, errors.Errorf("$graphLookup requires from, startWith, connectFromField, connectToField and as")
+	}
+
+	maxDepth := int64(-1)
+	if value, exists := get(graphLookup, "maxDepth"); exists {
+		maxDepth = integer(value)
+	}
+	restrict := bson.D{}
+	if value, exists := get(graphLookup, "restrictSearchWithMatch"); exists {
+		if document, ok := value.(bson.D); ok {
+			restrict = document
+		}
+	}
+
+	foreign, err := c.match(c.database.documents(from), restrict)
+	if err != nil {
+		return nil, err
+	}
+
+	joined := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		start, _ := get(document, strings.TrimPrefix(startWith, "$"))
+		frontier := bson.A{start}
+		if array, ok := start.(bson.A); ok {
+			frontier = array
+		}
+
+		var found []bson.D
+		visited := map[int]bool{}
+		for depth := int64(0); len(frontier) > 0 && (maxDepth < 0 || depth <= maxDepth); depth++ {
+			var next bson.A
+			for i, candidate := range foreign {
+				if visited[i] {
+					continue
+				}
+				ok, err := c.database.evaluator.Match(
+					bson.D{{Key: connectTo, Value: bson.D{{Key: "$in", Value: frontier}}}}, candidate)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+
+				visited[i] = true
+				if depthField != "" {
+					candidate = set(candidate, depthField, depth)
+				}
+				found = append(found, candidate)
+				if value, exists := get(candidate, connectFrom); exists {
+					if array, ok := value.(bson.A); ok {
+						next = append(next, array...)
+					} else {
+						next = append(next, value)
+					}
+				}
+			}
+			frontier = next
+		}
+		joined = append(joined, set(document, as, values(found)))
+	}
+	return joined, nil
+}
+
+// unionWith appends the documents returned by the pipeline run against the documents of the collection
+func (c *collection) unionWith(documents []bson.D, unionWith bson.D) ([]bson.D, error) {
+	name := stringOf(unionWith, "coll")
+	if name == "" {
+		return nil, errors.Errorf("$unionWith requires coll")
+	}
+
+	var stages []bson.D
+	if value, exists := get(unionWith, "pipeline"); exists {
+		array, ok := value.(bson.A)
+		if !ok {
+			return nil, errors.Errorf("$unionWith pipeline must be an array")
+		}
+		for _, stage := range array {
+			stageDocument, ok := stage.(bson.D)
+			if !ok {
+				return nil, errors.Errorf("$unionWith pipeline must be an array of documents")
+			}
+			stages = append(stages, stageDocument)
+		}
+	}
+
+	other := &collection{database: c.database, name: name}
+	union, err := other.aggregate(c.database.documents(name), stages)
+	if err != nil {
+		return nil, err
+	}
+	return append(documents, union...), nil
+}
+
+// sort sorts the documents by the keys of the sort document (1 ascending, -1 descending).
+// The sort is stable, so the documents with equal keys keep their order.
+func (c *collection) sort(documents []bson.D, sortDocument bson.D) {
+	sort.SliceStable(documents, func(i, j int) bool {
+		for _, key := range sortDocument {
+			a, _ := get(documents[i], key.Key)
+			b, _ := get(documents[j], key.Key)
+
+			result := c.database.evaluator.Compare(a, b)
+			if integer(key.Value) < 0 {
+				result = -result
+			}
+			if result != 0 {
+				return result < 0
+			}
+		}
+		return false
+	})
+}
+
+// unwind outputs a document for every element of the array at the path
+// (the documents with a missing or empty array are only kept if preserveNullAndEmptyArrays is set)
+func unwind(documents []bson.D, spec interface{}) ([]bson.D, error) {
+	var path string
+	var preserve bool
+	switch spec := spec.(type) {
+	case string:
+		path = spec
+	case bson.D:
+		path = stringOf(spec, "path")
+		if value, exists := get(spec, "preserveNullAndEmptyArrays"); exists {
+			preserve, _ = value.(bool)
+		}
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.Errorf("$unwind requires a path starting with $")
+	}
+	path = strings.TrimPrefix(path, "$")
+
+	unwound := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		value, exists := get(document, path)
+		array, isArray := value.(bson.A)
+		switch {
+		case !exists || value == nil || (isArray && len(array) == 0):
+			if preserve {
+				unwound = append(unwound, document)
+			}
+		case isArray:
+			for _, element := range array {
+				unwound = append(unwound, set(document, path, element))
+			}
+		default:
+			unwound = append(unwound, document)
+		}
+	}
+	return unwound, nil
+}
+
+// project keeps the paths of the projection set to 1 (and _id unless it is set to 0)
+// or removes the paths set to 0 if no path is set to 1
+func project(documents []bson.D, projection bson.D) []bson.D {
+	var included, excluded []string
+	keepID := true
+	for _, path := range projection {
+		switch {
+		case path.Key == "_id" && integer(path.Value) == 0:
+			keepID = false
+		case integer(path.Value) == 0:
+			excluded = append(excluded, path.Key)
+		default:
+			included = append(included, path.Key)
+		}
+	}
+
+	projected := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		if len(included) == 0 {
+			result := document
+			for _, path := range excluded {
+				result = unset(result, path)
+			}
+			if !keepID {
+				result = unset(result, "_id")
+			}
+			projected = append(projected, result)
+			continue
+		}
+
+		result := bson.D{}
+		if id, exists := get(document, "_id"); exists && keepID {
+			result = append(result, bson.E{Key: "_id", Value: id})
+		}
+		for _, path := range included {
+			if value, exists := get(document, path); exists {
+				result = set(result, path, value)
+			}
+		}
+		projected = append(projected, result)
+	}
+	return projected
+}
+
+// get returns the value at the dotted path of the document
+func get(document bson.D, path string) (interface{}, bool) {
+	var value interface{} = document
+	for _, key := range strings.Split(path, ".") {
+		current, ok := value.(bson.D)
+		if !ok {
+			return nil, false
+		}
+
+		found := false
+		for _, element := range current {
+			if element.Key == key {
+				value, found = element.Value, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// set returns a copy of the document with the value at the dotted path
+// (the missing documents of the path are created)
+func set(document bson.D, path string, value interface{}) bson.D {
+	key, rest, nested := strings.Cut(path, ".")
+	result := append(bson.D{}, document...)
+
+	for i, element := range result {
+		if element.Key != key {
+			continue
+		}
+		if nested {
+			child, _ := element.Value.(bson.D)
+			value = set(child, rest, value)
+		}
+		result[i] = bson.E{Key: key, Value: value}
+		return result
+	}
+
+	if nested {
+		value = set(bson.D{}, rest, value)
+	}
+	return append(result, bson.E{Key: key, Value: value})
+}
+
+// unset returns a copy of the document without the value at the dotted path
+func unset(document bson.D, path string) bson.D {
+	key, rest, nested := strings.Cut(path, ".")
+	result := make(bson.D, 0, len(document))
+
+	for _, element := range document {
+		if element.Key != key {
+			result = append(result, element)
+			continue
+		}
+		if child, ok := element.Value.(bson.D); ok && nested {
+			result = append(result, bson.E{Key: key, Value: unset(child, rest)})
+		}
+	}
+	return result
+}
+
+// skipDocuments returns the documents following the first skip ones
+func skipDocuments(documents []bson.D, skip int64) []bson.D {
+	if skip >= int64(len(documents)) {
+		return []bson.D{}
+	}
+	if skip > 0 {
+		return documents[skip:]
+	}
+	return documents
+}
+
+// limitDocuments returns the first limit documents (a negative limit is the same as the positive one)
+func limitDocuments(documents []bson.D, limit int64) []bson.D {
+	if limit < 0 {
+		limit = -limit
+	}
+	if limit > 0 && limit < int64(len(documents)) {
+		return documents[:limit]
+	}
+	return documents
+}
+
+// stringOf returns the string value of the key of the document
+func stringOf(document bson.D, key string) string {
+	value, _ := get(document, key)
+	text, _ := value.(string)
+	return text
+}
+
+// integer converts the numeric value to int64
+func integer(value interface{}) int64 {
+	switch value := value.(type) {
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case float64:
+		return int64(value)
+	case int:
+		return int64(value)
+	}
+	return 0
+}
+
+// document returns the value of the stage as a document
+func document(stage bson.E) (bson.D, error) {
+	document, ok := stage.Value.(bson.D)
+	if !ok {
+		return nil, errors.Errorf("stage %s requires a document", stage.Key)
+	}
+	return document, nil
+}
+
+// values converts the documents to the values of a bson array
+func values(documents []bson.D) bson.A {
+	array := make(bson.A, 0, len(documents))
+	for _, document := range documents {
+		array = append(array, document)
+	}
+	return array
+}
+
+// normalize marshals the value to bson and unmarshals it back,
+// so that the values are of the types the driver decodes (e.g. int32, bson.A)
+func normalize(value interface{}) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	document := bson.D{}
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// normalizePipeline normalizes the stages of the pipeline (e.g. mongo.Pipeline)
+func normalizePipeline(pipeline interface{}) ([]bson.D, error) {
+	wrapper, err := normalize(bson.D{{Key: "pipeline", Value: pipeline}})
+	if err != nil {
+		return nil, errors.Wrap(err, "pipeline is not valid")
+	}
+
+	array, ok := wrapper[0].Value.(bson.A)
+	if !ok {
+		return nil, errors.Errorf("pipeline must be an array")
+	}
+
+	stages := make([]bson.D, 0, len(array))
+	for _, stage := range array {
+		stageDocument, ok := stage.(bson.D)
+		if !ok {
+			return nil, errors.Errorf("pipeline must be an array of documents")
+		}
+		stages = append(stages, stageDocument)
+	}
+	return stages, nil
+}
+
+// NewDatabase creates a new empty database evaluating the documents using the provided evaluator
+func NewDatabase(evaluator evaluator.IEvaluator) IDatabase {
+	return &database{
+		collections: map[string][]bson.D{},
+		evaluator:   evaluator,
+	}
+}
@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/evaluator"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/repository"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Job struct {
+	ID        int      `bson:"_id"`
+	Title     string   `bson:"title"`
+	Salary    int      `bson:"salary"`
+	CompanyID string   `bson:"companyId"`
+	Tags      []string `bson:"tags"`
+}
+
+type CompanyFilter struct {
+	Name *string `filter:"name" operator:"eq"`
+}
+
+type JobFilter struct {
+	Salary  *int          `filter:"salary" operator:"gte"`
+	Company CompanyFilter `relation:"companies,local=companyId,foreign=_id,as=company"`
+	Sort    []string      `filter:"sort"`
+	Limit   int64         `filter:"limit"`
+	Offset  int64         `filter:"offset"`
+}
+
+func newDatabase(t *testing.T) IDatabase {
+	database := NewDatabase(evaluator.NewEvaluator())
+	assert.NoError(t, database.Insert("jobs",
+		Job{ID: 1, Title: "golang", Salary: 3000, CompanyID: "acme", Tags: []string{"go", "mongo"}},
+		Job{ID: 2, Title: "python", Salary: 2000, CompanyID: "globex", Tags: []string{"python"}},
+		Job{ID: 3, Title: "rust", Salary: 4000, CompanyID: "acme"},
+	))
+	assert.NoError(t, database.Insert("companies",
+		bson.D{{Key: "_id", Value: "acme"}, {Key: "name", Value: "Acme"}},
+		bson.D{{Key: "_id", Value: "globex"}, {Key: "name", Value: "Globex"}},
+	))
+	return database
+}
+
+func decode(t *testing.T, cursor *mongo.Cursor, err error) []bson.D {
+	assert.NoError(t, err)
+	var documents []bson.D
+	assert.NoError(t, cursor.All(context.Background(), &documents))
+	return documents
+}
+
+func ids(documents []bson.D) []interface{} {
+	var values []interface{}
+	for _, document := range documents {
+		value, _ := get(document, "_id")
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestCollection_Find(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  interface{}
+		options *options.FindOptions
+		want    []interface{}
+	}{
+		{
+			name:    "Filter",
+			filter:  bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: 3000}}}},
+			options: options.Find(),
+			want:    []interface{}{int32(1), int32(3)},
+		},
+		{
+			name:    "Sort, skip and limit",
+			filter:  bson.D{},
+			options: options.Find().SetSort(bson.D{{Key: "salary", Value: -1}}).SetSkip(1).SetLimit(1),
+			want:    []interface{}{int32(1)},
+		},
+		{
+			name:    "Array semantics",
+			filter:  bson.M{"tags": "mongo"},
+			options: options.Find(),
+			want:    []interface{}{int32(1)},
+		},
+	}
+
+	collection := newDatabase(t).Collection("jobs")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := collection.Find(context.Background(), tt.filter, tt.options)
+			assert.Equal(t, tt.want, ids(decode(t, cursor, err)))
+		})
+	}
+}
+
+func TestCollection_Find_Projection(t *testing.T) {
+	collection := newDatabase(t).Collection("jobs")
+	cursor, err := collection.Find(context.Background(), bson.D{{Key: "_id", Value: 2}},
+		options.Find().SetProjection(bson.D{{Key: "title", Value: 1}}))
+
+	assert.Equal(t, []bson.D{{{Key: "_id", Value: int32(2)}, {Key: "title", Value: "python"}}},
+		decode(t, cursor, err))
+}
+
+func TestCollection_FindOne(t *testing.T) {
+	collection := newDatabase(t).Collection("jobs")
+
+	var job Job
+	err := collection.FindOne(context.Background(), bson.D{},
+		options.FindOne().SetSort(bson.D{{Key: "salary", Value: 1}})).Decode(&job)
+	assert.NoError(t, err)
+	assert.Equal(t, "python", job.Title)
+
+	err = collection.FindOne(context.Background(), bson.D{{Key: "title", Value: "java"}}).Decode(&job)
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+}
+
+func TestCollection_CountDocuments(t *testing.T) {
+	count, err := newDatabase(t).Collection("jobs").CountDocuments(context.Background(),
+		bson.D{{Key: "companyId", Value: "acme"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCollection_Distinct(t *testing.T) {
+	values, err := newDatabase(t).Collection("jobs").Distinct(context.Background(), "tags", bson.D{})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"go", "mongo", "python", nil}, values)
+}
+
+func TestCollection_Aggregate(t *testing.T) {
+	database := newDatabase(t)
+	assert.NoError(t, database.Insert("archive", Job{ID: 4, Title: "java", Salary: 5000, CompanyID: "globex"}))
+	assert.NoError(t, database.Insert("categories",
+		bson.D{{Key: "_id", Value: "backend"}},
+		bson.D{{Key: "_id", Value: "go"}, {Key: "parent", Value: "backend"}},
+		bson.D{{Key: "_id", Value: "goroutines"}, {Key: "parent", Value: "go"}},
+	))
+
+	tests := []struct {
+		name     string
+		pipeline mongo.Pipeline
+		want     []bson.D
+		wantErr  bool
+	}{
+		{
+			name: "Lookup and unwind",
+			pipeline: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "_id", Value: 2}}}},
+				{{Key: "$lookup", Value: bson.M{
+					"from": "companies", "localField": "companyId", "foreignField": "_id", "as": "company",
+				}}},
+				{{Key: "$unwind", Value: bson.M{"path": "$company", "preserveNullAndEmptyArrays": true}}},
+				{{Key: "$project", Value: bson.D{{Key: "company.name", Value: 1}}}},
+			},
+			want: []bson.D{{{Key: "_id", Value: int32(2)}, {Key: "company", Value: bson.D{{Key: "name", Value: "Globex"}}}}},
+		},
+		{
+			name: "Unwind without preserving empty arrays",
+			pipeline: mongo.Pipeline{
+				{{Key: "$unwind", Value: "$tags"}},
+				{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "tags", Value: 1}}}},
+			},
+			want: []bson.D{
+				{{Key: "tags", Value: "go"}}, {{Key: "tags", Value: "mongo"}}, {{Key: "tags", Value: "python"}},
+			},
+		},
+		{
+			name: "Union with sort and limit",
+			pipeline: mongo.Pipeline{
+				{{Key: "$unionWith", Value: bson.D{{Key: "coll", Value: "archive"}, {Key: "pipeline", Value: mongo.Pipeline{}}}}},
+				{{Key: "$sort", Value: bson.D{{Key: "salary", Value: -1}}}},
+				{{Key: "$limit", Value: int64(1)}},
+				{{Key: "$project", Value: bson.D{{Key: "title", Value: 1}}}},
+			},
+			want: []bson.D{{{Key: "_id", Value: int32(4)}, {Key: "title", Value: "java"}}},
+		},
+		{
+			name: "Unsupported stage",
+			pipeline: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "_id", Value: 1}}}},
+				{{Key: "$set", Value: bson.D{}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	collection := database.Collection("jobs")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := collection.Aggregate(context.Background(), tt.pipeline)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if err == nil {
+				assert.Equal(t, tt.want, decode(t, cursor, err))
+			}
+		})
+	}
+
+	cursor, err := database.Collection("categories").Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "_id", Value: "goroutines"}}}},
+		{{Key: "$graphLookup", Value: bson.D{
+			{Key: "from", Value: "categories"},
+			{Key: "startWith", Value: "$parent"},
+			{Key: "connectFromField", Value: "parent"},
+			{Key: "connectToField", Value: "_id"},
+			{Key: "as", Value: "ancestors"},
+			{Key: "depthField", Value: "depth"},
+		}}},
+	})
+	documents := decode(t, cursor, err)
+	assert.Len(t, documents, 1)
+	ancestors, _ := get(documents[0], "ancestors")
+	assert.Equal(t, bson.A{
+		bson.D{{Key: "_id", Value: "go"}, {Key: "parent", Value: "backend"}, {Key: "depth", Value: int64(0)}},
+		bson.D{{Key: "_id", Value: "backend"}, {Key: "depth", Value: int64(1)}},
+	}, ancestors)
+}
+
+func TestCollection_Repository(t *testing.T) {
+	jobs := repository.NewRepository[Job](newDatabase(t).Collection("jobs"),
+		scanner.NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "relation"),
+		builder.NewFilterBuilder(),
+		paginator.NewPaginator(10, 100))
+
+	acme, salary := "Acme", 2500
+	filter := JobFilter{Salary: &salary, Company: CompanyFilter{Name: &acme}, Sort: []string{"-salary"}, Limit: 1}
+
+	found, err := jobs.Aggregate(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []Job{{ID: 3, Title: "rust", Salary: 4000, CompanyID: "acme"}}, found)
+
+	total, err := jobs.Count(context.Background(), JobFilter{Salary: &salary})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
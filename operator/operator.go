@@ -108,7 +108,7 @@ func (o GTOperator) IsCompatible(fieldType reflect.Kind) bool {
 }
 
 func (o GTEOperator) ExternalName() string {
-	return "gt"
+	return "gte"
 }
 
 // GTEOperator is the greater than or equal operator (>=)
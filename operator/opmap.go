@@ -47,3 +47,20 @@ func NewOperatorMap() IOperatorMap {
 		},
 	}
 }
+
+// NewUpdateOperatorMap creates the map of the update operators
+// the fields of update structs name in their update tag (e.g. `update:"inc"`).
+func NewUpdateOperatorMap() IOperatorMap {
+	return &operatorMap{
+		source: map[string]IOperator{
+			"set":      SetOperator{},
+			"unset":    UnsetOperator{},
+			"inc":      IncOperator{},
+			"push":     PushOperator{},
+			"addToSet": AddToSetOperator{},
+			"pull":     PullOperator{},
+			"min":      MinOperator{},
+			"max":      MaxOperator{},
+		},
+	}
+}
@@ -0,0 +1,125 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package operator
+
+import "reflect"
+
+// isNumber checks whether the field type is numeric
+func isNumber(fieldType reflect.Kind) bool {
+	return fieldType == reflect.Int ||
+		fieldType == reflect.Int8 ||
+		fieldType == reflect.Int16 ||
+		fieldType == reflect.Int32 ||
+		fieldType == reflect.Int64 ||
+		fieldType == reflect.Uint ||
+		fieldType == reflect.Uint8 ||
+		fieldType == reflect.Uint16 ||
+		fieldType == reflect.Uint32 ||
+		fieldType == reflect.Uint64 ||
+		fieldType == reflect.Float32 ||
+		fieldType == reflect.Float64
+}
+
+// SetOperator is the set update operator (field = value)
+// Compatible types: all
+type SetOperator struct{}
+
+func (o SetOperator) ExternalName() string {
+	return "set"
+}
+
+func (o SetOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return true
+}
+
+// UnsetOperator is the unset update operator (removes the field)
+// Compatible types: all
+type UnsetOperator struct{}
+
+func (o UnsetOperator) ExternalName() string {
+	return "unset"
+}
+
+func (o UnsetOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return true
+}
+
+// IncOperator is the increment update operator (field += value)
+// Compatible types: int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64
+type IncOperator struct{}
+
+func (o IncOperator) ExternalName() string {
+	return "inc"
+}
+
+func (o IncOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return isNumber(fieldType)
+}
+
+// PushOperator is the push update operator (appends the value or the values to the array)
+// Compatible types: all
+type PushOperator struct{}
+
+func (o PushOperator) ExternalName() string {
+	return "push"
+}
+
+func (o PushOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return true
+}
+
+// AddToSetOperator is the add to set update operator
+// (appends the value or the values to the array unless they are in it already)
+// Compatible types: all
+type AddToSetOperator struct{}
+
+func (o AddToSetOperator) ExternalName() string {
+	return "addToSet"
+}
+
+func (o AddToSetOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return true
+}
+
+// PullOperator is the pull update operator (removes the value or the values from the array)
+// Compatible types: all
+type PullOperator struct{}
+
+func (o PullOperator) ExternalName() string {
+	return "pull"
+}
+
+func (o PullOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return true
+}
+
+// MinOperator is the min update operator (field = min(field, value))
+// Compatible types: string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64
+type MinOperator struct{}
+
+func (o MinOperator) ExternalName() string {
+	return "min"
+}
+
+func (o MinOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return fieldType == reflect.String || isNumber(fieldType)
+}
+
+// MaxOperator is the max update operator (field = max(field, value))
+// Compatible types: string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64
+type MaxOperator struct{}
+
+func (o MaxOperator) ExternalName() string {
+	return "max"
+}
+
+func (o MaxOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return fieldType == reflect.String || isNumber(fieldType)
+}
@@ -0,0 +1,163 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package paginator
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tieBreaker is the sort key appended to every keyset sort,
+// so that the documents with the same values of the other sort keys are ordered as well
+const tieBreaker = "_id"
+
+// KeysetSort returns the sort with the _id tie breaker appended (unless it is sorted by already).
+// Keyset pagination only works if the sort orders all the documents,
+// i.e. if no two documents have the same values of all the sort keys.
+func KeysetSort(sort bson.D) bson.D {
+	for _, key := range sort {
+		if key.Key == tieBreaker {
+			return sort
+		}
+	}
+	return append(sort[:len(sort):len(sort)], bson.E{Key: tieBreaker, Value: 1})
+}
+
+// EncodeCursor encodes the values of the sort keys of the document (usually the last one of a page)
+// into an opaque cursor the next page starts after. The sort keys may be dotted paths.
+func EncodeCursor(sort bson.D, document interface{}) (string, error) {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return "", errors.Wrap(err, "document can not be encoded")
+	}
+
+	values := bson.D{}
+	for _, key := range KeysetSort(sort) {
+		value, err := bson.Raw(raw).LookupErr(strings.Split(key.Key, ".")...)
+		if err != nil {
+			return "", errors.Errorf("document has no value of sort key %s", key.Key)
+		}
+		values = append(values, bson.E{Key: key.Key, Value: value})
+	}
+
+	cursor, err := bson.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "cursor can not be encoded")
+	}
+	return base64.RawURLEncoding.EncodeToString(cursor), nil
+}
+
+// DecodeCursor decodes the values of the sort keys encoded by EncodeCursor
+func DecodeCursor(cursor string) (bson.D, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Errorf("cursor %s is not valid", cursor)
+	}
+
+	var values bson.D
+	if err := bson.Unmarshal(raw, &values); err != nil {
+		return nil, errors.Errorf("cursor %s is not valid", cursor)
+	}
+	return values, nil
+}
+
+// KeysetFields returns the fields matching the documents following the cursor in the sort order
+// (the sort is tie broken by _id the same way as by KeysetSort), e.g. for the sort {a: 1}
+// {$or: [{a: {$gt: x}}, {$and: [{a: {$eq: x}}, {_id: {$gt: y}}]}]}.
+// The alternatives are combined using a group, so the fields can be added to a builder
+// along with the other fields of the collection. The fields are indexed starting from the index.
+// The fields of the sort keys on the paths of joined documents (e.g. company.name) carry the relation
+// stored at the longest prefix of the key among the provided relations, so that they are matched after the join.
+func KeysetFields(collection string, relations []*field.Relation, sort bson.D, cursor bson.D,
+	index int) ([]field.IFilterField, error) {
+	values := map[string]interface{}{}
+	for _, value := range cursor {
+		values[value.Key] = value.Value
+	}
+
+	sort = KeysetSort(sort)
+	keyset := field.NewGroup("keyset", "or", nil)
+
+	var fields []field.IFilterField
+	for i, key := range sort {
+		value, exists := values[key.Key]
+		if !exists {
+			return nil, errors.Errorf("cursor has no value of sort key %s", key.Key)
+		}
+
+		var op operator.IOperator = operator.GTOperator{}
+		if descending(key.Value) {
+			op = operator.LTOperator{}
+		}
+
+		// every alternative requires the previous sort keys to be equal
+		// to the values of the cursor and the current one to follow its value
+		// (the first one consists of a single field and needs no group of its own)
+		alternative := keyset
+		if i > 0 {
+			alternative = field.NewGroup("keyset"+strconv.Itoa(i), "and", keyset)
+		}
+		for _, previous := range sort[:i] {
+			fields = append(fields, newField(collection, relationOf(relations, previous.Key), previous.Key,
+				values[previous.Key], operator.EQOperator{}, index+len(fields)).SetGroup(alternative))
+		}
+		fields = append(fields, newField(collection, relationOf(relations, key.Key), key.Key,
+			value, op, index+len(fields)).SetGroup(alternative))
+	}
+	return fields, nil
+}
+
+// newField creates a new keyset filter field (of the collection of the relation, if any)
+func newField(collection string, relation *field.Relation, name string, value interface{},
+	op operator.IOperator, index int) field.IFilterField {
+	if relation != nil {
+		collection = relation.Collection
+	}
+
+	keysetField := field.NewFilterField(collection, reflect.ValueOf(value).Kind().String(), name, value, op, index)
+	if relation != nil {
+		keysetField.SetRelation(relation)
+	}
+	return keysetField
+}
+
+// relationOf returns the relation whose joined document the sort key is on,
+// i.e. the one stored at the longest prefix of the key, or nil if the key is not on a joined document
+func relationOf(relations []*field.Relation, key string) *field.Relation {
+	var found *field.Relation
+	for _, relation := range relations {
+		if strings.HasPrefix(key, relation.As+".") && (found == nil || len(relation.As) > len(found.As)) {
+			found = relation
+		}
+	}
+	return found
+}
+
+// descending checks whether the sort order is descending (-1)
+func descending(order interface{}) bool {
+	switch order := order.(type) {
+	case int:
+		return order < 0
+	case int32:
+		return order < 0
+	case int64:
+		return order < 0
+	case float64:
+		return order < 0
+	}
+	return false
+}
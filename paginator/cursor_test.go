@@ -0,0 +1,78 @@
+package paginator
+
+import (
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestKeysetSort(t *testing.T) {
+	sort := bson.D{{Key: "postedAt", Value: -1}}
+	assert.Equal(t, bson.D{{Key: "postedAt", Value: -1}, {Key: "_id", Value: 1}}, KeysetSort(sort))
+	assert.Equal(t, bson.D{{Key: "postedAt", Value: -1}}, sort)
+
+	sort = bson.D{{Key: "_id", Value: -1}}
+	assert.Equal(t, sort, KeysetSort(sort))
+}
+
+func TestCursor(t *testing.T) {
+	sort := bson.D{{Key: "salary.min", Value: -1}, {Key: "title", Value: 1}}
+	document := bson.D{
+		{Key: "_id", Value: "job1"},
+		{Key: "title", Value: "golang"},
+		{Key: "salary", Value: bson.D{{Key: "min", Value: int32(5000)}}},
+	}
+
+	cursor, err := EncodeCursor(sort, document)
+	assert.NoError(t, err)
+
+	got, err := DecodeCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "salary.min", Value: int32(5000)},
+		{Key: "title", Value: "golang"},
+		{Key: "_id", Value: "job1"},
+	}, got)
+
+	_, err = EncodeCursor(bson.D{{Key: "postedAt", Value: 1}}, document)
+	assert.Error(t, err)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.Error(t, err)
+}
+
+func TestKeysetFields(t *testing.T) {
+	sort := bson.D{{Key: "postedAt", Value: -1}}
+	cursor := bson.D{{Key: "postedAt", Value: int64(100)}, {Key: "_id", Value: "job1"}}
+
+	got, err := KeysetFields("jobs", nil, sort, cursor, 5)
+	assert.NoError(t, err)
+
+	keyset := field.NewGroup("keyset", "or", nil)
+	alternative := field.NewGroup("keyset1", "and", keyset)
+	assert.Equal(t, []field.IFilterField{
+		field.NewFilterField("jobs", "int64", "postedAt", int64(100), operator.LTOperator{}, 5).
+			SetGroup(keyset),
+		field.NewFilterField("jobs", "int64", "postedAt", int64(100), operator.EQOperator{}, 6).
+			SetGroup(alternative),
+		field.NewFilterField("jobs", "string", "_id", "job1", operator.GTOperator{}, 7).
+			SetGroup(alternative),
+	}, got)
+
+	// the fields of the sort keys on joined documents carry the relation of the longest prefix
+	company := field.NewRelation("companies", "companyId", "_id", "company")
+	industry := field.NewRelation("industries", "company.industryId", "_id", "company.industry")
+	got, err = KeysetFields("jobs", []*field.Relation{company, industry},
+		bson.D{{Key: "company.industry.name", Value: 1}}, bson.D{{Key: "company.industry.name", Value: "it"},
+			{Key: "_id", Value: "job1"}}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, industry, got[0].GetRelation())
+	assert.Equal(t, "industries", got[0].GetCollection())
+	assert.Nil(t, got[2].GetRelation())
+
+	_, err = KeysetFields("jobs", nil, sort, bson.D{{Key: "postedAt", Value: int64(100)}}, 0)
+	assert.Error(t, err)
+}
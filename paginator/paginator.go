@@ -0,0 +1,92 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package paginator
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Pagination holds the number of documents to skip
+// and the maximum number of documents to return (0 means unlimited).
+type Pagination struct {
+	Skip  int64
+	Limit int64
+}
+
+// IPaginator is used to turn the pagination requested by the user
+// (either page/pageSize or limit/offset) into a Pagination.
+// The page size is capped by the max page size of the paginator,
+// so that the user can not request the whole collection at once.
+type IPaginator interface {
+	// Page returns the pagination of the page (starting from 1) of the provided size
+	Page(page int64, pageSize int64) (Pagination, error)
+
+	// Offset returns the pagination of the limit documents following the first offset ones
+	Offset(limit int64, offset int64) (Pagination, error)
+}
+
+type paginator struct {
+	defaultPageSize int64
+	maxPageSize     int64
+}
+
+// Page returns the pagination of the page (starting from 1) of the provided size.
+// The first page is returned if the page is not set (0)
+// and the default page size is used if the page size is not set (0).
+func (p *paginator) Page(page int64, pageSize int64) (Pagination, error) {
+	if page < 0 {
+		return Pagination{}, errors.Errorf("page %d is negative", page)
+	}
+	if pageSize < 0 {
+		return Pagination{}, errors.Errorf("page size %d is negative", pageSize)
+	}
+
+	if page == 0 {
+		page = 1
+	}
+	pageSize = p.pageSize(pageSize)
+	if pageSize == 0 && page > 1 {
+		return Pagination{}, errors.Errorf("page %d requires a page size", page)
+	}
+	return Pagination{Skip: (page - 1) * pageSize, Limit: pageSize}, nil
+}
+
+// Offset returns the pagination of the limit documents following the first offset ones.
+// The default page size is used if the limit is not set (0).
+func (p *paginator) Offset(limit int64, offset int64) (Pagination, error) {
+	if limit < 0 {
+		return Pagination{}, errors.Errorf("limit %d is negative", limit)
+	}
+	if offset < 0 {
+		return Pagination{}, errors.Errorf("offset %d is negative", offset)
+	}
+	return Pagination{Skip: offset, Limit: p.pageSize(limit)}, nil
+}
+
+// pageSize returns the requested page size (or the default one if it is not set)
+// capped by the max page size
+func (p *paginator) pageSize(pageSize int64) int64 {
+	if pageSize == 0 {
+		pageSize = p.defaultPageSize
+	}
+	if p.maxPageSize > 0 && (pageSize == 0 || pageSize > p.maxPageSize) {
+		pageSize = p.maxPageSize
+	}
+	return pageSize
+}
+
+// NewPaginator creates a new paginator with the provided default and max page sizes
+// (0 means the page size is not limited).
+func NewPaginator(defaultPageSize int64, maxPageSize int64) IPaginator {
+	return &paginator{
+		defaultPageSize: defaultPageSize,
+		maxPageSize:     maxPageSize,
+	}
+}
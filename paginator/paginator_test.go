@@ -0,0 +1,117 @@
+package paginator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginator_Page(t *testing.T) {
+	tests := []struct {
+		name      string
+		paginator IPaginator
+		page      int64
+		pageSize  int64
+		want      Pagination
+		wantErr   bool
+	}{
+		{
+			name:      "Page is skipped by the previous pages",
+			paginator: NewPaginator(20, 100),
+			page:      3,
+			pageSize:  10,
+			want:      Pagination{Skip: 20, Limit: 10},
+		},
+		{
+			name:      "Unset page and page size yield the first page of the default size",
+			paginator: NewPaginator(20, 100),
+			want:      Pagination{Skip: 0, Limit: 20},
+		},
+		{
+			name:      "Page size is capped by the max page size",
+			paginator: NewPaginator(20, 100),
+			page:      2,
+			pageSize:  1000,
+			want:      Pagination{Skip: 100, Limit: 100},
+		},
+		{
+			name:      "Unlimited paginator returns the first page unlimited",
+			paginator: NewPaginator(0, 0),
+			want:      Pagination{},
+		},
+		{
+			name:      "Page without page size is rejected by an unlimited paginator",
+			paginator: NewPaginator(0, 0),
+			page:      2,
+			wantErr:   true,
+		},
+		{
+			name:      "Negative page is rejected",
+			paginator: NewPaginator(20, 100),
+			page:      -1,
+			wantErr:   true,
+		},
+		{
+			name:      "Negative page size is rejected",
+			paginator: NewPaginator(20, 100),
+			pageSize:  -1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.paginator.Page(tt.page, tt.pageSize)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPaginator_Offset(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		offset  int64
+		want    Pagination
+		wantErr bool
+	}{
+		{
+			name:   "Limit documents are returned after the offset",
+			limit:  10,
+			offset: 5,
+			want:   Pagination{Skip: 5, Limit: 10},
+		},
+		{
+			name:   "Unset limit yields the default page size",
+			offset: 5,
+			want:   Pagination{Skip: 5, Limit: 20},
+		},
+		{
+			name:  "Limit is capped by the max page size",
+			limit: 1000,
+			want:  Pagination{Skip: 0, Limit: 100},
+		},
+		{
+			name:    "Negative offset is rejected",
+			offset:  -5,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPaginator(20, 100).Offset(tt.limit, tt.offset)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
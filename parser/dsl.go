@@ -0,0 +1,267 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+)
+
+// dslOperators maps the comparison operators of the query DSL to the names of the operators
+var dslOperators = map[string]string{
+	"=":  "eq",
+	"!=": "ne",
+	">":  "gt",
+	">=": "gte",
+	"<":  "lt",
+	"<=": "lte",
+	"~":  "regex",
+}
+
+// lexDSL splits the query into tokens.
+// The keywords (AND, OR, NOT, IN) and the booleans (true, false) are case insensitive,
+// and the strings are quoted by double or single quotes (a backslash escapes the next character).
+func lexDSL(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r, position := runes[i], i+1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, token{kind: string(r), text: string(r), position: position})
+			i++
+		case r == '"' || r == '\'':
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, syntaxError(position, "string is not terminated")
+			}
+			tokens = append(tokens, token{kind: "string", text: text.String(), position: position})
+			i++
+		case strings.ContainsRune("=!<>~", r):
+			text := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				text += "="
+			}
+			if _, exists := dslOperators[text]; !exists {
+				return nil, syntaxError(position, "operator %s is not supported", text)
+			}
+			tokens = append(tokens, token{kind: "operator", text: text, position: position})
+			i += len(text)
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			start := i
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])); i++ {
+			}
+			tokens = append(tokens, token{kind: "number", text: string(runes[start:i]), position: position})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i++; i < len(runes) && (runes[i] == '_' || runes[i] == '.' ||
+				unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])); i++ {
+			}
+			text := string(runes[start:i])
+			switch strings.ToUpper(text) {
+			case "AND", "OR", "NOT", "IN":
+				tokens = append(tokens, token{kind: "keyword", text: strings.ToUpper(text), position: position})
+			case "TRUE", "FALSE":
+				tokens = append(tokens, token{kind: "bool", text: strings.ToLower(text), position: position})
+			default:
+				tokens = append(tokens, token{kind: "ident", text: text, position: position})
+			}
+		default:
+			return nil, syntaxError(position, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: "eof", text: "end of query", position: len(runes) + 1}), nil
+}
+
+// dslParser is the recursive descent parser of the query DSL:
+//
+//	query      = or
+//	or         = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = "NOT" unary | "(" or ")" | comparison
+//	comparison = field operator value | field [ "NOT" ] "IN" "(" value { "," value } ")"
+//	operator   = "=" | "!=" | ">" | ">=" | "<" | "<=" | "~"
+//	value      = string | number | "true" | "false"
+//
+// e.g. salary >= 5000 AND (title ~ "go" OR remote = true)
+type dslParser struct {
+	operatorMap operator.IOperatorMap
+	schema      []field.IFilterField
+}
+
+// dslSyntax holds the state of parsing a query (the parser is shared by the queries)
+type dslSyntax struct {
+	cursor
+}
+
+// Parse parses the query into a list of IFilterField indexed starting from the index.
+// The empty query yields no fields.
+func (p *dslParser) Parse(query string, index int) ([]field.IFilterField, error) {
+	tokens, err := lexDSL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	parsing := &dslSyntax{cursor: cursor{tokens: tokens}}
+	if parsing.peek().kind == "eof" {
+		return []field.IFilterField{}, nil
+	}
+
+	root, err := parsing.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := parsing.peek(); next.kind != "eof" {
+		return nil, syntaxError(next.position, "unexpected %s", next.text)
+	}
+
+	b := newFieldBuilder(p.operatorMap, p.schema, index)
+	if err := b.build(root, nil); err != nil {
+		return nil, err
+	}
+	return b.fields, nil
+}
+
+func (p *dslSyntax) or() (*node, error) {
+	children, err := p.sequence("OR", p.and)
+	if err != nil {
+		return nil, err
+	}
+	return combine("or", children), nil
+}
+
+func (p *dslSyntax) and() (*node, error) {
+	children, err := p.sequence("AND", p.unary)
+	if err != nil {
+		return nil, err
+	}
+	return combine("and", children), nil
+}
+
+// sequence parses the operands separated by the keyword
+func (p *dslSyntax) sequence(keyword string, operand func() (*node, error)) ([]*node, error) {
+	var children []*node
+	for {
+		child, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+
+		if !p.keyword(keyword) {
+			return children, nil
+		}
+		p.next()
+	}
+}
+
+func (p *dslSyntax) unary() (*node, error) {
+	switch {
+	case p.keyword("NOT"):
+		p.next()
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{method: "nor", children: []*node{child}}, nil
+	case p.peek().kind == "(":
+		p.next()
+		child, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")", ")"); err != nil {
+			return nil, err
+		}
+		return child, nil
+	}
+	return p.comparison()
+}
+
+func (p *dslSyntax) comparison() (*node, error) {
+	name, err := p.expect("ident", "field")
+	if err != nil {
+		return nil, err
+	}
+	comparison := &node{name: name.text, position: name.position}
+
+	if p.peek().kind == "operator" {
+		comparison.operator = dslOperators[p.next().text]
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		comparison.values = []literal{value}
+		return comparison, nil
+	}
+
+	comparison.operator = "in"
+	if p.keyword("NOT") {
+		p.next()
+		comparison.operator = "nin"
+	}
+	if !p.keyword("IN") {
+		return nil, syntaxError(p.peek().position, "expected operator, found %s", p.peek().text)
+	}
+	p.next()
+
+	if _, err := p.expect("(", "("); err != nil {
+		return nil, err
+	}
+	comparison.list = true
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		comparison.values = append(comparison.values, value)
+
+		if p.peek().kind != "," {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(")", ")"); err != nil {
+		return nil, err
+	}
+	return comparison, nil
+}
+
+func (p *dslSyntax) value() (literal, error) {
+	switch t := p.peek(); t.kind {
+	case "string", "number", "bool":
+		p.next()
+		return literal{kind: t.kind, text: t.text, position: t.position}, nil
+	}
+	return literal{}, syntaxError(p.peek().position, "expected value, found %s", p.peek().text)
+}
+
+// NewDSLParser creates a new parser of the query DSL (e.g. salary >= 5000 AND (title ~ "go" OR remote = true))
+// resolving the operators using the operator map and filtering by the fields of the schema.
+func NewDSLParser(operatorMap operator.IOperatorMap, schema []field.IFilterField) IParser {
+	return &dslParser{
+		operatorMap: operatorMap,
+		schema:      schema,
+	}
+}
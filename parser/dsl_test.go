@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CompanyFilter struct {
+	Name *string `filter:"name" operator:"eq"`
+}
+
+type JobFilter struct {
+	Title   *string       `filter:"title" operator:"regex"`
+	Salary  *int          `filter:"salary" operator:"gte"`
+	Remote  *bool         `filter:"remote" operator:"eq"`
+	Tags    []string      `filter:"tags" operator:"in"`
+	Company CompanyFilter `relation:"companies,local=companyId,foreign=_id,as=company"`
+}
+
+func schema(t *testing.T) []field.IFilterField {
+	schema, err := scanner.NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "relation").
+		ScanSchema(JobFilter{})
+	assert.NoError(t, err)
+	return schema
+}
+
+func TestDSLParser_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    bson.D
+		wantErr string
+	}{
+		{
+			name:  "Empty query",
+			query: "  ",
+			want:  bson.D{},
+		},
+		{
+			name:  "Comparisons combined by and",
+			query: `salary >= 5000 and remote = TRUE AND title != 'java'`,
+			want: bson.D{
+				{Key: "salary", Value: bson.D{{Key: "$gte", Value: 5000}}},
+				{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}},
+				{Key: "title", Value: bson.D{{Key: "$ne", Value: "java"}}},
+			},
+		},
+		{
+			name:  "Parentheses group the alternatives",
+			query: `salary >= 5000 AND (title ~ "go" OR remote = true)`,
+			want: bson.D{
+				{Key: "salary", Value: bson.D{{Key: "$gte", Value: 5000}}},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "go"}}}},
+					bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+				}},
+			},
+		},
+		{
+			name:  "And binds stronger than or",
+			query: `remote = true OR salary > 100 AND salary < 200`,
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "salary", Value: bson.D{{Key: "$gt", Value: 100}}}},
+					bson.D{{Key: "salary", Value: bson.D{{Key: "$lt", Value: 200}}}},
+				}}},
+			}}},
+		},
+		{
+			name:  "Lists and negation",
+			query: `tags NOT IN ("php", "java") AND NOT title ~ "junior"`,
+			want: bson.D{
+				{Key: "tags", Value: bson.D{{Key: "$nin", Value: []string{"php", "java"}}}},
+				{Key: "$nor", Value: bson.A{
+					bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "junior"}}}},
+				}},
+			},
+		},
+		{
+			name:  "Scalar field filtered by a list",
+			query: `company.name in ("Acme", "Globex")`,
+			want: bson.D{
+				{Key: "company.name", Value: bson.D{{Key: "$in", Value: []string{"Acme", "Globex"}}}},
+			},
+		},
+		{
+			name:    "Unknown field",
+			query:   `salary > 1 AND views > 10`,
+			wantErr: "syntax error at position 16: field views can not be filtered by",
+		},
+		{
+			name:    "Value of another type",
+			query:   `salary > "a lot"`,
+			wantErr: "syntax error at position 10: value a lot is not a int",
+		},
+		{
+			name:    "Regex of a number field",
+			query:   `salary ~ "5"`,
+			wantErr: "syntax error at position 10: value 5 is not a int",
+		},
+		{
+			name:    "Operator incompatible with the type",
+			query:   `remote > true`,
+			wantErr: "syntax error at position 1: operator gt is not compatible with field remote of type bool",
+		},
+		{
+			name:    "Missing parenthesis",
+			query:   `(salary > 1 OR remote = true`,
+			wantErr: "syntax error at position 29: expected ), found end of query",
+		},
+		{
+			name:    "Missing operator",
+			query:   `salary 5`,
+			wantErr: "syntax error at position 8: expected operator, found 5",
+		},
+		{
+			name:    "Unterminated string",
+			query:   `title ~ "go`,
+			wantErr: "syntax error at position 9: string is not terminated",
+		},
+		{
+			name:    "Unexpected token",
+			query:   `salary > 1 remote = true`,
+			wantErr: "syntax error at position 12: unexpected remote",
+		},
+		{
+			name:    "Unsupported character",
+			query:   `salary & 1`,
+			wantErr: "syntax error at position 8: unexpected character '&'",
+		},
+	}
+
+	parser := NewDSLParser(operator.NewOperatorMap(), schema(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := parser.Parse(tt.query, 0)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				_, ok := err.(*SyntaxError)
+				assert.True(t, ok)
+				return
+			}
+			assert.NoError(t, err)
+
+			filter := builder.NewFilterBuilder().SetFields(fields).Build()
+			assert.NoError(t, filter.Err())
+			assert.Equal(t, tt.want, filter.Output())
+		})
+	}
+}
+
+func TestDSLParser_Parse_Relations(t *testing.T) {
+	fields, err := NewDSLParser(operator.NewOperatorMap(), schema(t)).Parse(`company.name = "Acme"`, 3)
+	assert.NoError(t, err)
+	assert.Len(t, fields, 1)
+	assert.Equal(t, 3, fields[0].GetIndex())
+	assert.Equal(t, "companies", fields[0].GetCollection())
+
+	pipeline := builder.NewFilterBuilder().SetFields(fields).Build().Pipeline()
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from": "companies", "localField": "companyId", "foreignField": "_id", "as": "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$company", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$match", Value: bson.D{{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "Acme"}}}}}},
+	}, pipeline)
+}
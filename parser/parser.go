@@ -0,0 +1,276 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+)
+
+// IParser is used to parse textual queries typed by the users into filter fields.
+// The fields combined by logical operators are put into logical groups (field.Group),
+// so that the builder combines them the same way as the query does.
+// Only the fields of the schema (see IScanner.ScanSchema) can be filtered by,
+// and the values are converted to the types of the schema fields.
+type IParser interface {
+	// Parse parses the query into a list of IFilterField indexed starting from the index
+	Parse(query string, index int) ([]field.IFilterField, error)
+}
+
+// SyntaxError is the error of a query which can not be parsed,
+// annotated with the position (starting from 1) of the character it occurred at.
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// syntaxError creates a new SyntaxError with the formatted message
+func syntaxError(position int, format string, args ...interface{}) error {
+	return &SyntaxError{Position: position, Message: fmt.Sprintf(format, args...)}
+}
+
+// token is a token of a query along with its position
+type token struct {
+	// kind is the kind of the token (e.g. ident, string, number, operator, "(" or eof)
+	kind     string
+	text     string
+	position int
+}
+
+// cursor holds the tokens of a query and the position of the current one
+type cursor struct {
+	tokens  []token
+	current int
+}
+
+// peek returns the current token
+func (c *cursor) peek() token {
+	return c.tokens[c.current]
+}
+
+// next returns the current token and moves to the next one
+func (c *cursor) next() token {
+	t := c.tokens[c.current]
+	if t.kind != "eof" {
+		c.current++
+	}
+	return t
+}
+
+// keyword checks whether the current token is the keyword
+func (c *cursor) keyword(keyword string) bool {
+	return c.peek().kind == "keyword" && c.peek().text == keyword
+}
+
+// expect returns the current token if it is of the kind or error otherwise
+func (c *cursor) expect(kind string, expected string) (token, error) {
+	if c.peek().kind != kind {
+		return token{}, syntaxError(c.peek().position, "expected %s, found %s", expected, c.peek().text)
+	}
+	return c.next(), nil
+}
+
+// literal is a value of a query along with its position
+type literal struct {
+	// kind is the token kind of the value (string, number, bool or text, which is typed by the field)
+	kind     string
+	text     string
+	position int
+}
+
+// node is a node of the syntax tree of a query:
+// either a comparison of a field or the logical combination of its children
+type node struct {
+	// method is the merge method of the logical node (and, or, nor)
+	method   string
+	children []*node
+
+	name     string
+	operator string
+	values   []literal
+	list     bool
+	position int
+}
+
+// combine returns the logical node combining the children by the method.
+// The children combined by the same method are flattened and a single child is returned as it is.
+func combine(method string, children []*node) *node {
+	if len(children) == 1 {
+		return children[0]
+	}
+
+	combined := &node{method: method}
+	for _, child := range children {
+		if child.method == method {
+			combined.children = append(combined.children, child.children...)
+			continue
+		}
+		combined.children = append(combined.children, child)
+	}
+	return combined
+}
+
+// fieldBuilder turns the syntax tree of a query into filter fields
+type fieldBuilder struct {
+	operatorMap operator.IOperatorMap
+	schema      map[string]field.IFilterField
+	fields      []field.IFilterField
+	index       int
+	groups      int
+}
+
+// newFieldBuilder creates a new fieldBuilder of the fields of the schema
+func newFieldBuilder(operatorMap operator.IOperatorMap, schema []field.IFilterField, index int) *fieldBuilder {
+	fields := map[string]field.IFilterField{}
+	for _, schemaField := range schema {
+		if _, exists := fields[schemaField.GetName()]; !exists {
+			fields[schemaField.GetName()] = schemaField
+		}
+	}
+	return &fieldBuilder{operatorMap: operatorMap, schema: fields, fields: []field.IFilterField{}, index: index}
+}
+
+// build adds the fields of the node to the group. The conjunction of the root is not a group,
+// since the fields which are not in any group are combined by and anyway.
+func (b *fieldBuilder) build(n *node, group *field.Group) error {
+	if n.method == "" {
+		return b.comparison(n, group)
+	}
+
+	childGroup := group
+	if group != nil || n.method != "and" {
+		b.groups++
+		childGroup = field.NewGroup("group"+strconv.Itoa(b.groups), n.method, group)
+	}
+
+	for _, child := range n.children {
+		if err := b.build(child, childGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// comparison adds the field compared by the node to the group
+func (b *fieldBuilder) comparison(n *node, group *field.Group) error {
+	schemaField, exists := b.schema[n.name]
+	if !exists {
+		return syntaxError(n.position, "field %s can not be filtered by", n.name)
+	}
+
+	op := b.operatorMap.Get(n.operator)
+	if op == nil {
+		return syntaxError(n.position, "operator %s is not supported", n.operator)
+	}
+
+	value, err := convert(n, reflect.TypeOf(schemaField.GetValue()))
+	if err != nil {
+		return err
+	}
+	if !op.IsCompatible(value.Kind()) {
+		return syntaxError(n.position, "operator %s is not compatible with field %s of type %s",
+			n.operator, n.name, value.Kind())
+	}
+
+	filterField := field.NewFilterField(schemaField.GetCollection(), value.Kind().String(),
+		n.name, value.Interface(), op, b.index)
+	if schemaField.GetRelation() != nil {
+		filterField.SetRelation(schemaField.GetRelation())
+	}
+	if group != nil {
+		filterField.SetGroup(group)
+	}
+
+	b.fields = append(b.fields, filterField)
+	b.index++
+	return nil
+}
+
+// convert converts the values of the node to the type of the field
+// (a list of values to a slice of the element type of the field)
+func convert(n *node, fieldType reflect.Type) (reflect.Value, error) {
+	elementType := fieldType
+	if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+		elementType = fieldType.Elem()
+	}
+
+	if !n.list {
+		return convertLiteral(n.values[0], elementType)
+	}
+
+	values := reflect.MakeSlice(reflect.SliceOf(elementType), 0, len(n.values))
+	for _, value := range n.values {
+		converted, err := convertLiteral(value, elementType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		values = reflect.Append(values, converted)
+	}
+	return values, nil
+}
+
+// convertLiteral converts the literal to the type
+func convertLiteral(value literal, valueType reflect.Type) (reflect.Value, error) {
+	converted := reflect.New(valueType).Elem()
+
+	// the untyped values (e.g. the arguments of rsql) are typed by the type of the field
+	if value.kind == "text" {
+		switch valueType.Kind() {
+		case reflect.String:
+			value.kind = "string"
+		case reflect.Bool:
+			if value.text == "true" || value.text == "false" {
+				value.kind = "bool"
+			}
+		default:
+			value.kind = "number"
+		}
+	}
+
+	var err error
+	switch {
+	case value.kind == "string" && valueType.Kind() == reflect.String:
+		converted.SetString(value.text)
+		return converted, nil
+	case value.kind == "bool" && valueType.Kind() == reflect.Bool:
+		converted.SetBool(value.text == "true")
+		return converted, nil
+	case value.kind == "number":
+		switch valueType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var number int64
+			if number, err = strconv.ParseInt(value.text, 10, valueType.Bits()); err == nil {
+				converted.SetInt(number)
+				return converted, nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var number uint64
+			if number, err = strconv.ParseUint(value.text, 10, valueType.Bits()); err == nil {
+				converted.SetUint(number)
+				return converted, nil
+			}
+		case reflect.Float32, reflect.Float64:
+			var number float64
+			if number, err = strconv.ParseFloat(value.text, valueType.Bits()); err == nil {
+				converted.SetFloat(number)
+				return converted, nil
+			}
+		}
+	}
+	return reflect.Value{}, syntaxError(value.position, "value %s is not a %s", value.text, valueType.Kind())
+}
@@ -0,0 +1,348 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package parser
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+)
+
+// rsqlOperators maps the comparison operators of RSQL/FIQL to the names of the operators.
+// The other FIQL operators (=name=) name the operators of the operator map directly,
+// so that the custom operators registered in it can be used as well (e.g. =regex=).
+var rsqlOperators = map[string]string{
+	"==":    "eq",
+	"!=":    "ne",
+	"=gt=":  "gt",
+	">":     "gt",
+	"=ge=":  "gte",
+	">=":    "gte",
+	"=lt=":  "lt",
+	"<":     "lt",
+	"=le=":  "lte",
+	"<=":    "lte",
+	"=in=":  "in",
+	"=out=": "nin",
+}
+
+// rsqlReserved are the characters which can not be a part of an unquoted selector or argument
+const rsqlReserved = `"'();,=!<>~`
+
+// lexRSQL splits the RSQL query into tokens. The selectors and the unquoted arguments are text tokens
+// (typed by the fields they are compared with) and the quoted arguments are strings
+// (a backslash escapes the next character).
+func lexRSQL(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r, position := runes[i], i+1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ';' || r == ',':
+			tokens = append(tokens, token{kind: string(r), text: string(r), position: position})
+			i++
+		case r == '"' || r == '\'':
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				// the escaped wildcards are kept escaped, so that they are not wildcards
+				if runes[i] == '\\' && i+1 < len(runes) {
+					if runes[i+1] == '*' {
+						text.WriteRune('\\')
+					}
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, syntaxError(position, "string is not terminated")
+			}
+			tokens = append(tokens, token{kind: "string", text: text.String(), position: position})
+			i++
+		case r == '=':
+			end := i + 1
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			if end == len(runes) || runes[end] != '=' {
+				return nil, syntaxError(position, "operator %s is not terminated", string(runes[i:end]))
+			}
+			tokens = append(tokens, token{kind: "operator", text: string(runes[i : end+1]), position: position})
+			i = end + 1
+		case r == '!' || r == '<' || r == '>':
+			text := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				text += "="
+			}
+			if text == "!" {
+				return nil, syntaxError(position, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: "operator", text: text, position: position})
+			i += len(text)
+		case !strings.ContainsRune(rsqlReserved, r):
+			start := i
+			for i++; i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(rsqlReserved, runes[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: "text", text: string(runes[start:i]), position: position})
+		default:
+			return nil, syntaxError(position, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: "eof", text: "end of query", position: len(runes) + 1}), nil
+}
+
+// rsqlParser is the parser of RSQL/FIQL queries:
+//
+//	query      = or
+//	or         = and { "," and }
+//	and        = constraint { ";" constraint }
+//	constraint = "(" or ")" | comparison
+//	comparison = selector operator ( argument | "(" argument { "," argument } ")" )
+//	operator   = "==" | "!=" | "=gt=" | ">" | "=ge=" | ">=" | "=lt=" | "<" | "=le=" | "<=" | "=in=" | "=out=" | "=name="
+//
+// e.g. title==go*;salary=ge=5000,remote==true
+// The arguments of == and != containing wildcards (*) compared with string fields are matched
+// as patterns (e.g. go* matches the strings starting with go) using the regex operator.
+type rsqlParser struct {
+	operatorMap operator.IOperatorMap
+	schema      []field.IFilterField
+}
+
+// rsqlSyntax holds the state of parsing a query (the parser is shared by the queries)
+type rsqlSyntax struct {
+	cursor
+}
+
+// Parse parses the query into a list of IFilterField indexed starting from the index.
+// The empty query yields no fields.
+func (p *rsqlParser) Parse(query string, index int) ([]field.IFilterField, error) {
+	tokens, err := lexRSQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	parsing := &rsqlSyntax{cursor: cursor{tokens: tokens}}
+	if parsing.peek().kind == "eof" {
+		return []field.IFilterField{}, nil
+	}
+
+	root, err := parsing.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := parsing.peek(); next.kind != "eof" {
+		return nil, syntaxError(next.position, "unexpected %s", next.text)
+	}
+
+	b := newFieldBuilder(p.operatorMap, p.schema, index)
+	if root, err = wildcards(root, b.schema); err != nil {
+		return nil, err
+	}
+	if err := b.build(root, nil); err != nil {
+		return nil, err
+	}
+	return b.fields, nil
+}
+
+func (p *rsqlSyntax) or() (*node, error) {
+	children, err := p.sequence(",", p.and)
+	if err != nil {
+		return nil, err
+	}
+	return combine("or", children), nil
+}
+
+func (p *rsqlSyntax) and() (*node, error) {
+	children, err := p.sequence(";", p.constraint)
+	if err != nil {
+		return nil, err
+	}
+	return combine("and", children), nil
+}
+
+// sequence parses the operands separated by the separator
+func (p *rsqlSyntax) sequence(separator string, operand func() (*node, error)) ([]*node, error) {
+	var children []*node
+	for {
+		child, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+
+		if p.peek().kind != separator {
+			return children, nil
+		}
+		p.next()
+	}
+}
+
+func (p *rsqlSyntax) constraint() (*node, error) {
+	if p.peek().kind != "(" {
+		return p.comparison()
+	}
+
+	p.next()
+	child, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(")", ")"); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+func (p *rsqlSyntax) comparison() (*node, error) {
+	selector, err := p.expect("text", "selector")
+	if err != nil {
+		return nil, err
+	}
+	comparison := &node{name: selector.text, position: selector.position}
+
+	op, err := p.expect("operator", "operator")
+	if err != nil {
+		return nil, err
+	}
+	comparison.operator = rsqlOperators[op.text]
+	if comparison.operator == "" {
+		comparison.operator = strings.Trim(op.text, "=")
+	}
+
+	if p.peek().kind == "(" {
+		p.next()
+		comparison.list = true
+		for {
+			value, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			comparison.values = append(comparison.values, unescape(value))
+
+			if p.peek().kind != "," {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(")", ")"); err != nil {
+			return nil, err
+		}
+		return comparison, nil
+	}
+
+	value, err := p.argument()
+	if err != nil {
+		return nil, err
+	}
+	comparison.values = []literal{value}
+	return comparison, nil
+}
+
+func (p *rsqlSyntax) argument() (literal, error) {
+	switch t := p.peek(); t.kind {
+	case "text", "string":
+		p.next()
+		return literal{kind: t.kind, text: t.text, position: t.position}, nil
+	}
+	return literal{}, syntaxError(p.peek().position, "expected argument, found %s", p.peek().text)
+}
+
+// wildcards turns the comparisons of the string fields by == or != with values containing wildcards (*)
+// into patterns matched by the regex operator (the strings not matching them for !=).
+// Escaped wildcards (\*) are literal, and the wildcards compared with the fields of other types are reported.
+func wildcards(n *node, schema map[string]field.IFilterField) (*node, error) {
+	if n.method != "" {
+		for i, child := range n.children {
+			replaced, err := wildcards(child, schema)
+			if err != nil {
+				return nil, err
+			}
+			n.children[i] = replaced
+		}
+		return n, nil
+	}
+
+	// the values of lists are never patterns and are unescaped already
+	if n.list {
+		return n, nil
+	}
+
+	value := n.values[0]
+	pattern, found := wildcardPattern(value.text)
+	schemaField, exists := schema[n.name]
+	if !found || !exists || (n.operator != "eq" && n.operator != "ne") {
+		n.values = []literal{unescape(value)}
+		return n, nil
+	}
+
+	elementType := reflect.TypeOf(schemaField.GetValue())
+	if elementType.Kind() == reflect.Slice || elementType.Kind() == reflect.Array {
+		elementType = elementType.Elem()
+	}
+	if elementType.Kind() != reflect.String {
+		return nil, syntaxError(value.position, "value %s has wildcards, which only match string fields (%s is a %s)",
+			value.text, n.name, elementType.Kind())
+	}
+
+	matched := &node{
+		name:     n.name,
+		operator: "regex",
+		values:   []literal{{kind: "string", text: pattern, position: value.position}},
+		position: n.position,
+	}
+	if n.operator == "ne" {
+		return &node{method: "nor", children: []*node{matched}}, nil
+	}
+	return matched, nil
+}
+
+// wildcardPattern returns the anchored regular expression matching the strings the text with wildcards does
+// and whether the text contains any wildcards (which are not escaped)
+func wildcardPattern(text string) (string, bool) {
+	var pattern strings.Builder
+	found := false
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '*':
+			pattern.WriteString(regexp.QuoteMeta("*"))
+			i++
+		case runes[i] == '*':
+			pattern.WriteString(".*")
+			found = true
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	return "^" + pattern.String() + "$", found
+}
+
+// unescape replaces the escaped wildcards of the value by the wildcards
+func unescape(value literal) literal {
+	value.text = strings.ReplaceAll(value.text, `\*`, "*")
+	return value
+}
+
+// NewRSQLParser creates a new parser of RSQL/FIQL queries (e.g. title==go*;salary=ge=5000,remote==true)
+// resolving the operators using the operator map and filtering by the fields of the schema.
+func NewRSQLParser(operatorMap operator.IOperatorMap, schema []field.IFilterField) IParser {
+	return &rsqlParser{
+		operatorMap: operatorMap,
+		schema:      schema,
+	}
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// allOperator is a custom operator matching the arrays containing all the values
+type allOperator struct{}
+
+func (o allOperator) ExternalName() string {
+	return "all"
+}
+
+func (o allOperator) IsCompatible(fieldType reflect.Kind) bool {
+	return fieldType == reflect.Slice
+}
+
+func TestRSQLParser_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    bson.D
+		wantErr string
+	}{
+		{
+			name:  "Empty query",
+			query: "",
+			want:  bson.D{},
+		},
+		{
+			name:  "And binds stronger than or",
+			query: `title==go*;salary=ge=5000,remote==true`,
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "^go.*$"}}}},
+					bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: 5000}}}},
+				}}},
+				bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+			}}},
+		},
+		{
+			name:  "Parentheses group the alternatives",
+			query: `salary>5000;(title=="Go developer",remote!=false)`,
+			want: bson.D{
+				{Key: "salary", Value: bson.D{{Key: "$gt", Value: 5000}}},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "Go developer"}}}},
+					bson.D{{Key: "remote", Value: bson.D{{Key: "$ne", Value: false}}}},
+				}},
+			},
+		},
+		{
+			name:  "Comparison operators",
+			query: `salary=gt=1;salary=lt=9;salary>=2;salary<=8;salary=le=7`,
+			want: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$gt", Value: 1}}}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$lt", Value: 9}}}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: 2}}}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$lte", Value: 8}}}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$lte", Value: 7}}}},
+			}}},
+		},
+		{
+			name:  "Lists",
+			query: `tags=in=(go,'rust');company.name=out=("Acme",Globex)`,
+			want: bson.D{
+				{Key: "tags", Value: bson.D{{Key: "$in", Value: []string{"go", "rust"}}}},
+				{Key: "company.name", Value: bson.D{{Key: "$nin", Value: []string{"Acme", "Globex"}}}},
+			},
+		},
+		{
+			name:  "Wildcards of not equal",
+			query: `title!=*junior*`,
+			want: bson.D{{Key: "$nor", Value: bson.A{
+				bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "^.*junior.*$"}}}},
+			}}},
+		},
+		{
+			name:  "Escaped wildcards and special characters",
+			query: `title=="c++ \*";company.name==a.b*`,
+			want: bson.D{
+				{Key: "title", Value: bson.D{{Key: "$eq", Value: "c++ *"}}},
+				{Key: "company.name", Value: bson.D{{Key: "$regex", Value: `^a\.b.*$`}}},
+			},
+		},
+		{
+			name:  "Wildcards of a list field match its elements",
+			query: `tags==go*`,
+			want:  bson.D{{Key: "tags", Value: bson.D{{Key: "$regex", Value: "^go.*$"}}}},
+		},
+		{
+			name:  "Operators of the operator map",
+			query: `title=regex=^go;tags=all=(go,docker)`,
+			want: bson.D{
+				{Key: "title", Value: bson.D{{Key: "$regex", Value: "^go"}}},
+				{Key: "tags", Value: bson.D{{Key: "$all", Value: []string{"go", "docker"}}}},
+			},
+		},
+		{
+			name:    "Unknown field",
+			query:   `salary>1;views>10`,
+			wantErr: "syntax error at position 10: field views can not be filtered by",
+		},
+		{
+			name:    "Unknown operator",
+			query:   `salary=near=1`,
+			wantErr: "syntax error at position 1: operator near is not supported",
+		},
+		{
+			name:    "Value of another type",
+			query:   `salary==lots`,
+			wantErr: "syntax error at position 9: value lots is not a int",
+		},
+		{
+			name:    "Wildcards of a number field",
+			query:   `salary==5*`,
+			wantErr: "syntax error at position 9: value 5* has wildcards, which only match string fields (salary is a int)",
+		},
+		{
+			name:    "Not a boolean",
+			query:   `remote==yes`,
+			wantErr: "syntax error at position 9: value yes is not a bool",
+		},
+		{
+			name:    "Unterminated operator",
+			query:   `salary=ge5000`,
+			wantErr: "syntax error at position 7: operator =ge is not terminated",
+		},
+		{
+			name:    "Missing argument",
+			query:   `salary>=;remote==true`,
+			wantErr: "syntax error at position 9: expected argument, found ;",
+		},
+		{
+			name:    "Missing parenthesis",
+			query:   `(salary>1,remote==true`,
+			wantErr: "syntax error at position 23: expected ), found end of query",
+		},
+		{
+			name:    "Unexpected token",
+			query:   `salary>1)`,
+			wantErr: "syntax error at position 9: unexpected )",
+		},
+	}
+
+	operatorMap := operator.NewOperatorMap()
+	operatorMap.Set("all", allOperator{})
+	parser := NewRSQLParser(operatorMap, schema(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := parser.Parse(tt.query, 0)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				_, ok := err.(*SyntaxError)
+				assert.True(t, ok)
+				return
+			}
+			assert.NoError(t, err)
+
+			filter := builder.NewFilterBuilder().SetFields(fields).Build()
+			assert.NoError(t, filter.Err())
+			assert.Equal(t, tt.want, filter.Output())
+		})
+	}
+}
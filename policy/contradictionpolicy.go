@@ -0,0 +1,56 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package policy
+
+import (
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IContradictionPolicy is used to define what happens to the fields (IFilterField)
+// which can never match at the same time (e.g. age eq 5 and age eq 6).
+// It is injected into the IFilterBuilder as a dependency and either
+// reports the contradiction or replaces the fields with its own output.
+type IContradictionPolicy interface {
+	Resolve(contradiction error, fields ...field.IFilterField) (bson.D, error)
+}
+
+// errorContradictionPolicy resolves contradictions using the `error` method.
+// (i.e. the contradiction is reported as an error and the fields are left as they are)
+type errorContradictionPolicy struct {
+	method string
+}
+
+func (c *errorContradictionPolicy) Resolve(contradiction error, fields ...field.IFilterField) (bson.D, error) {
+	return nil, contradiction
+}
+
+func NewErrorContradictionPolicy() IContradictionPolicy {
+	return &errorContradictionPolicy{
+		method: "error",
+	}
+}
+
+// matchNothingContradictionPolicy resolves contradictions using the `matchNothing` method.
+// (i.e. the fields are replaced with a condition no document matches, {_id: {$in: []}},
+// so that the query short-circuits to an empty result instead of failing)
+type matchNothingContradictionPolicy struct {
+	method string
+}
+
+func (c *matchNothingContradictionPolicy) Resolve(contradiction error, fields ...field.IFilterField) (bson.D, error) {
+	return bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{}}}}}, nil
+}
+
+func NewMatchNothingContradictionPolicy() IContradictionPolicy {
+	return &matchNothingContradictionPolicy{
+		method: "matchNothing",
+	}
+}
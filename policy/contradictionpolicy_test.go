@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestContradictionPolicy_Resolve(t *testing.T) {
+	contradiction := errors.New("field age can not be equal to both 5 and 6")
+	fields := []field.IFilterField{
+		field.NewFilterField("", reflect.Int.String(), "age", 5, operator.EQOperator{}, 0),
+		field.NewFilterField("", reflect.Int.String(), "age", 6, operator.EQOperator{}, 1),
+	}
+
+	tests := []struct {
+		name    string
+		policy  IContradictionPolicy
+		want    bson.D
+		wantErr error
+	}{
+		{
+			name:    "Error policy reports the contradiction",
+			policy:  NewErrorContradictionPolicy(),
+			wantErr: contradiction,
+		},
+		{
+			name:   "Match nothing policy replaces the fields with a condition no document matches",
+			policy: NewMatchNothingContradictionPolicy(),
+			want:   bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{}}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Resolve(contradiction, fields...)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -12,21 +12,22 @@ package policy
 import (
 	"github.com/jobsearch-demos/mongo-filter-struct/field"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// IJoinPolicy is used to build bson filter which
-// joins two fields (IFilterField) from different collections.
+// IJoinPolicy is used to build the aggregation stages which
+// join the collection of a field (IFilterField) to the collection the filter is run against.
 // It is moved to a separate interface to ensure that the
 // IFilterField interface is not polluted with the logic
 // related to joining fields, since there are different
 // types of joins (left, right, inner, etc.) and each of them
 // has its own logic.
-// It is injected into the IFilterField as a dependency
-// to perform the join.
+// It is injected into the IFilterBuilder as a dependency
+// to emit the aggregation stages that perform the join.
 type IJoinPolicy interface {
-	Join(left, right field.IFilterField) bson.D
-	getLookup(left, right field.IFilterField) bson.M
-	getUnwind(left, right field.IFilterField) bson.M
+	// Join returns the aggregation stages joining the collection of the relation
+	// using its local and foreign fields and storing the result under its `as` key.
+	Join(relation *field.Relation) mongo.Pipeline
 }
 
 // leftOuterJoinPolicy joins two fields (IFilterField) from different collections
@@ -37,32 +38,26 @@ type leftOuterJoinPolicy struct {
 	method string
 }
 
-func (j *leftOuterJoinPolicy) getLookup(right, left field.IFilterField) bson.M {
+func (j *leftOuterJoinPolicy) getLookup(relation *field.Relation) bson.M {
 	return bson.M{
-		"from":         right.GetCollection(),
-		"localField":   left.GetName(),
-		"foreignField": right.GetName(),
-		"as":           right.GetName(),
+		"from":         relation.Collection,
+		"localField":   relation.LocalField,
+		"foreignField": relation.ForeignField,
+		"as":           relation.As,
 	}
 }
 
-func (j *leftOuterJoinPolicy) getUnwind(right, left field.IFilterField) bson.M {
+func (j *leftOuterJoinPolicy) getUnwind(relation *field.Relation) bson.M {
 	return bson.M{
-		"path":                       "$" + right.GetName(),
+		"path":                       "$" + relation.As,
 		"preserveNullAndEmptyArrays": true,
 	}
 }
 
-func (j *leftOuterJoinPolicy) Join(right, left field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   "$lookup",
-			Value: j.getLookup(right, left),
-		},
-		{
-			Key:   "$unwind",
-			Value: j.getUnwind(right, left),
-		},
+func (j *leftOuterJoinPolicy) Join(relation *field.Relation) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: j.getLookup(relation)}},
+		{{Key: "$unwind", Value: j.getUnwind(relation)}},
 	}
 }
 
@@ -71,3 +66,51 @@ func NewLeftOuterJoinPolicy() IJoinPolicy {
 		method: "leftOuter",
 	}
 }
+
+// graphLookupJoinPolicy joins the documents of a hierarchical collection (e.g. a category tree)
+// using the $graphLookup stage. (i.e. the document matching the local field is joined first,
+// then the documents connected to it via the `connectFrom` field are joined recursively,
+// and all of them are stored as an array under the `as` key.)
+// With `connectFrom` pointing at the parent (e.g. parentId), the joined documents are the
+// chain of parents of the related document, e.g. the category of a job and its parent categories,
+// so that filtering by category X matches the jobs whose category or one of its parents is X.
+type graphLookupJoinPolicy struct {
+	method string
+}
+
+func (j *graphLookupJoinPolicy) getGraphLookup(relation *field.Relation) bson.D {
+	graphLookup := bson.D{
+		{Key: "from", Value: relation.Collection},
+		{Key: "startWith", Value: "$" + relation.LocalField},
+		{Key: "connectFromField", Value: relation.ConnectFromField},
+		{Key: "connectToField", Value: relation.ForeignField},
+		{Key: "as", Value: relation.As},
+	}
+
+	if relation.MaxDepth != nil {
+		graphLookup = append(graphLookup, bson.E{Key: "maxDepth", Value: *relation.MaxDepth})
+	}
+
+	if relation.DepthField != "" {
+		graphLookup = append(graphLookup, bson.E{Key: "depthField", Value: relation.DepthField})
+	}
+
+	// the document has been validated while parsing the relation tag
+	var restrict bson.D
+	if relation.Restrict != "" && bson.UnmarshalExtJSON([]byte(relation.Restrict), false, &restrict) == nil {
+		graphLookup = append(graphLookup, bson.E{Key: "restrictSearchWithMatch", Value: restrict})
+	}
+	return graphLookup
+}
+
+func (j *graphLookupJoinPolicy) Join(relation *field.Relation) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$graphLookup", Value: j.getGraphLookup(relation)}},
+	}
+}
+
+func NewGraphLookupJoinPolicy() IJoinPolicy {
+	return &graphLookupJoinPolicy{
+		method: "graph",
+	}
+}
@@ -1 +1,111 @@
 package policy
+
+import (
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestLeftOuterJoinPolicy_Join(t *testing.T) {
+	got := NewLeftOuterJoinPolicy().Join(field.NewRelation("companies", "companyId", "_id", "company"))
+
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+	}, got)
+}
+
+func TestGraphLookupJoinPolicy_Join(t *testing.T) {
+	maxDepth, directOnly := 3, 0
+	tests := []struct {
+		name     string
+		relation *field.Relation
+		want     mongo.Pipeline
+	}{
+		{
+			name: "Join without optional options",
+			relation: &field.Relation{
+				Collection:       "categories",
+				LocalField:       "categoryId",
+				ForeignField:     "_id",
+				As:               "categories",
+				Policy:           "graph",
+				ConnectFromField: "parentId",
+			},
+			want: mongo.Pipeline{
+				{{Key: "$graphLookup", Value: bson.D{
+					{Key: "from", Value: "categories"},
+					{Key: "startWith", Value: "$categoryId"},
+					{Key: "connectFromField", Value: "parentId"},
+					{Key: "connectToField", Value: "_id"},
+					{Key: "as", Value: "categories"},
+				}}},
+			},
+		},
+		{
+			name: "Join with all options",
+			relation: &field.Relation{
+				Collection:       "categories",
+				LocalField:       "categoryId",
+				ForeignField:     "_id",
+				As:               "categories",
+				Policy:           "graph",
+				ConnectFromField: "parentId",
+				MaxDepth:         &maxDepth,
+				DepthField:       "depth",
+				Restrict:         `{"active": true}`,
+			},
+			want: mongo.Pipeline{
+				{{Key: "$graphLookup", Value: bson.D{
+					{Key: "from", Value: "categories"},
+					{Key: "startWith", Value: "$categoryId"},
+					{Key: "connectFromField", Value: "parentId"},
+					{Key: "connectToField", Value: "_id"},
+					{Key: "as", Value: "categories"},
+					{Key: "maxDepth", Value: 3},
+					{Key: "depthField", Value: "depth"},
+					{Key: "restrictSearchWithMatch", Value: bson.D{{Key: "active", Value: true}}},
+				}}},
+			},
+		},
+		{
+			name: "Join with zero max depth",
+			relation: &field.Relation{
+				Collection:       "categories",
+				LocalField:       "categoryId",
+				ForeignField:     "_id",
+				As:               "categories",
+				Policy:           "graph",
+				ConnectFromField: "parentId",
+				MaxDepth:         &directOnly,
+			},
+			want: mongo.Pipeline{
+				{{Key: "$graphLookup", Value: bson.D{
+					{Key: "from", Value: "categories"},
+					{Key: "startWith", Value: "$categoryId"},
+					{Key: "connectFromField", Value: "parentId"},
+					{Key: "connectToField", Value: "_id"},
+					{Key: "as", Value: "categories"},
+					{Key: "maxDepth", Value: 0},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewGraphLookupJoinPolicy().Join(tt.relation))
+		})
+	}
+}
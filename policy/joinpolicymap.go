@@ -0,0 +1,44 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package policy
+
+// IJoinPolicyMap is used to look up the join policy
+// a relation names in its `policy` option.
+type IJoinPolicyMap interface {
+	Get(name string) IJoinPolicy
+	Set(name string, joinPolicy IJoinPolicy) IJoinPolicy
+	SetSource(source map[string]IJoinPolicy)
+}
+
+type joinPolicyMap struct {
+	source map[string]IJoinPolicy
+}
+
+func (j *joinPolicyMap) Get(name string) IJoinPolicy {
+	return j.source[name]
+}
+
+func (j *joinPolicyMap) Set(name string, joinPolicy IJoinPolicy) IJoinPolicy {
+	j.source[name] = joinPolicy
+	return joinPolicy
+}
+
+func (j *joinPolicyMap) SetSource(source map[string]IJoinPolicy) {
+	j.source = source
+}
+
+func NewJoinPolicyMap() IJoinPolicyMap {
+	return &joinPolicyMap{
+		source: map[string]IJoinPolicy{
+			"leftOuter": NewLeftOuterJoinPolicy(),
+			"graph":     NewGraphLookupJoinPolicy(),
+		},
+	}
+}
@@ -10,34 +10,54 @@
 package policy
 
 import (
+	"strings"
+
 	"github.com/jobsearch-demos/mongo-filter-struct/field"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-// IMergePolicy is used to define the way of merging two fields (IFilterField).
+// IMergePolicy is used to define the way of merging several fields (IFilterField).
 // It is moved to a separate interface to ensure that the
 // IFilterField interface is not polluted with the logic
 // related to merging fields, since there are different
 // types of merging (override, and, or, etc.) and each of them
-// has its own logic. It is injected into the IFilterField
+// has its own logic. It is injected into the IFilterBuilder
 // as a dependency to perform different merge operations.
+// The merged output is a valid top-level query document,
+// e.g. {$or: [{age: {$lt: 18}}, {age: {$gt: 65}}]}, and merging no fields yields an empty document,
+// since mongodb rejects empty logical arrays (e.g. {$and: []}).
 type IMergePolicy interface {
-	Merge(left, right field.IFilterField) bson.D
+	Merge(fields ...field.IFilterField) bson.D
+}
+
+// IConjunctiveMergePolicy is implemented by the merge policies whose output
+// only matches if every merged field does (e.g. and, coalesce),
+// so that the merged fields can be checked for contradictions.
+type IConjunctiveMergePolicy interface {
+	IMergePolicy
+	Conjunctive() bool
+}
+
+// outputs returns the built outputs of the fields as an array of query documents
+func outputs(fields []field.IFilterField) bson.A {
+	documents := make(bson.A, 0, len(fields))
+	for _, fld := range fields {
+		documents = append(documents, fld.Build().Output())
+	}
+	return documents
 }
 
-// overrideMergePolicy merges two fields (IFilterField) from different collections
-// using the `override` method. (i.e. the right field overrides the left field)
+// overrideMergePolicy merges fields (IFilterField) using the `override` method.
+// (i.e. the last field overrides all the previous ones)
 type overrideMergePolicy struct {
 	method string
 }
 
-func (m *overrideMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: right.GetValue(),
-		},
+func (m *overrideMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
 	}
+	return fields[len(fields)-1].Build().Output()
 }
 
 func NewOverrideMergePolicy() IMergePolicy {
@@ -46,19 +66,21 @@ func NewOverrideMergePolicy() IMergePolicy {
 	}
 }
 
-// andMergePolicy merges two fields (IFilterField) from different collections
-// using the `and` method. (i.e. the left and right fields are merged using the and operator)
+// andMergePolicy merges fields (IFilterField) using the `and` method.
+// (i.e. the fields are merged using the $and operator: all of them have to match)
 type andMergePolicy struct {
 	method string
 }
 
-func (m *andMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: bson.D{{Key: "$and", Value: right.Build().Output()}},
-		},
+func (m *andMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
 	}
+	return bson.D{{Key: "$and", Value: outputs(fields)}}
+}
+
+func (m *andMergePolicy) Conjunctive() bool {
+	return true
 }
 
 func NewAndMergePolicy() IMergePolicy {
@@ -67,19 +89,66 @@ func NewAndMergePolicy() IMergePolicy {
 	}
 }
 
-// orMergePolicy merges two fields (IFilterField) from different collections
-// using the `or` method. (i.e. the left and right fields are merged using the or operator)
+// coalesceMergePolicy merges fields (IFilterField) using the `coalesce` method.
+// (i.e. the operators of the fields are combined into a single sub-document of their path,
+// e.g. {price: {$gte: 10, $lt: 100}}, and the fields are merged using the and method
+// if the same operator is used more than once or an output cannot be combined)
+type coalesceMergePolicy struct {
+	method string
+}
+
+func (m *coalesceMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
+	}
+
+	name := fields[0].GetName()
+	operators := bson.D{}
+	used := map[string]bool{}
+
+	for _, fld := range fields {
+		output := fld.Build().Output()
+		if len(output) != 1 || output[0].Key != name {
+			return NewAndMergePolicy().Merge(fields...)
+		}
+
+		fieldOperators, ok := output[0].Value.(bson.D)
+		if !ok {
+			return NewAndMergePolicy().Merge(fields...)
+		}
+
+		for _, op := range fieldOperators {
+			if used[op.Key] || !strings.HasPrefix(op.Key, "$") {
+				return NewAndMergePolicy().Merge(fields...)
+			}
+			used[op.Key] = true
+			operators = append(operators, op)
+		}
+	}
+	return bson.D{{Key: name, Value: operators}}
+}
+
+func (m *coalesceMergePolicy) Conjunctive() bool {
+	return true
+}
+
+func NewCoalesceMergePolicy() IMergePolicy {
+	return &coalesceMergePolicy{
+		method: "coalesce",
+	}
+}
+
+// orMergePolicy merges fields (IFilterField) using the `or` method.
+// (i.e. the fields are merged using the $or operator: at least one of them has to match)
 type orMergePolicy struct {
 	method string
 }
 
-func (m *orMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: bson.D{{Key: "$or", Value: right.Build().Output()}},
-		},
+func (m *orMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
 	}
+	return bson.D{{Key: "$or", Value: outputs(fields)}}
 }
 
 func NewOrMergePolicy() IMergePolicy {
@@ -88,17 +157,37 @@ func NewOrMergePolicy() IMergePolicy {
 	}
 }
 
+// xorMergePolicy merges fields (IFilterField) using the `xor` method.
+// (i.e. exactly one of the fields has to match)
+// Since there is no $xor operator in mongodb, it is emulated by requiring
+// every field to match while none of the others does:
+// {$or: [{$and: [A, {$nor: [B]}]}, {$and: [{$nor: [A]}, B]}]}
 type xorMergePolicy struct {
 	method string
 }
 
-func (m *xorMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: bson.D{{Key: "$xor", Value: right.Build().Output()}},
-		},
+func (m *xorMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	documents := outputs(fields)
+	switch len(fields) {
+	case 0:
+		return bson.D{}
+	case 1:
+		return fields[0].Output()
+	}
+
+	alternatives := make(bson.A, 0, len(documents))
+	for i := range documents {
+		alternative := make(bson.A, 0, len(documents))
+		for j, document := range documents {
+			if i == j {
+				alternative = append(alternative, document)
+				continue
+			}
+			alternative = append(alternative, bson.D{{Key: "$nor", Value: bson.A{document}}})
+		}
+		alternatives = append(alternatives, bson.D{{Key: "$and", Value: alternative}})
 	}
+	return bson.D{{Key: "$or", Value: alternatives}}
 }
 
 func NewXorMergePolicy() IMergePolicy {
@@ -107,17 +196,17 @@ func NewXorMergePolicy() IMergePolicy {
 	}
 }
 
+// notMergePolicy merges fields (IFilterField) using the `not` method.
+// (i.e. the fields must not all match at the same time: {$nor: [{$and: [...]}]})
 type notMergePolicy struct {
 	method string
 }
 
-func (m *notMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: bson.D{{Key: "$not", Value: right.Build().Output()}},
-		},
+func (m *notMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
 	}
+	return bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "$and", Value: outputs(fields)}}}}}
 }
 
 func NewNotMergePolicy() IMergePolicy {
@@ -126,17 +215,17 @@ func NewNotMergePolicy() IMergePolicy {
 	}
 }
 
+// norMergePolicy merges fields (IFilterField) using the `nor` method.
+// (i.e. the fields are merged using the $nor operator: none of them may match)
 type norMergePolicy struct {
 	method string
 }
 
-func (m *norMergePolicy) Merge(left, right field.IFilterField) bson.D {
-	return bson.D{
-		{
-			Key:   left.GetName(),
-			Value: bson.D{{Key: "$nor", Value: right.Build().Output()}},
-		},
+func (m *norMergePolicy) Merge(fields ...field.IFilterField) bson.D {
+	if len(fields) == 0 {
+		return bson.D{}
 	}
+	return bson.D{{Key: "$nor", Value: outputs(fields)}}
 }
 
 func NewNorMergePolicy() IMergePolicy {
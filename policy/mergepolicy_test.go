@@ -1 +1,171 @@
 package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// extJSON round-trips the merged output through bson.Marshal
+// and returns it as relaxed Extended JSON
+func extJSON(t *testing.T, document bson.D) string {
+	t.Helper()
+	data, err := bson.Marshal(document)
+	assert.NoError(t, err)
+	output, err := bson.MarshalExtJSON(bson.Raw(data), false, false)
+	assert.NoError(t, err)
+	return string(output)
+}
+
+func TestMergePolicy_Merge(t *testing.T) {
+	young := field.NewFilterField("", reflect.Int.String(), "age", 18, operator.LTOperator{}, 0)
+	old := field.NewFilterField("", reflect.Int.String(), "age", 65, operator.GTOperator{}, 1)
+
+	tests := []struct {
+		name   string
+		policy IMergePolicy
+		want   string
+	}{
+		{
+			name:   "Merge using override policy",
+			policy: NewOverrideMergePolicy(),
+			want:   `{"age":{"$gt":65}}`,
+		},
+		{
+			name:   "Merge using and policy",
+			policy: NewAndMergePolicy(),
+			want:   `{"$and":[{"age":{"$lt":18}},{"age":{"$gt":65}}]}`,
+		},
+		{
+			name:   "Merge using or policy",
+			policy: NewOrMergePolicy(),
+			want:   `{"$or":[{"age":{"$lt":18}},{"age":{"$gt":65}}]}`,
+		},
+		{
+			name:   "Merge using xor policy",
+			policy: NewXorMergePolicy(),
+			want: `{"$or":[` +
+				`{"$and":[{"age":{"$lt":18}},{"$nor":[{"age":{"$gt":65}}]}]},` +
+				`{"$and":[{"$nor":[{"age":{"$lt":18}}]},{"age":{"$gt":65}}]}` +
+				`]}`,
+		},
+		{
+			name:   "Merge using not policy",
+			policy: NewNotMergePolicy(),
+			want:   `{"$nor":[{"$and":[{"age":{"$lt":18}},{"age":{"$gt":65}}]}]}`,
+		},
+		{
+			name:   "Merge using nor policy",
+			policy: NewNorMergePolicy(),
+			want:   `{"$nor":[{"age":{"$lt":18}},{"age":{"$gt":65}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extJSON(t, tt.policy.Merge(young, old)))
+		})
+	}
+}
+
+func TestMergePolicy_Merge_NoFields(t *testing.T) {
+	policies := []IMergePolicy{
+		NewOverrideMergePolicy(),
+		NewAndMergePolicy(),
+		NewCoalesceMergePolicy(),
+		NewOrMergePolicy(),
+		NewXorMergePolicy(),
+		NewNotMergePolicy(),
+		NewNorMergePolicy(),
+	}
+
+	for _, mergePolicy := range policies {
+		assert.Equal(t, bson.D{}, mergePolicy.Merge(), "%T", mergePolicy)
+	}
+}
+
+func TestXorMergePolicy_Merge(t *testing.T) {
+	remote := field.NewFilterField("", reflect.Bool.String(), "remote", true, operator.EQOperator{}, 0)
+	city := field.NewFilterField("", reflect.String.String(), "city", "Baku", operator.EQOperator{}, 1)
+	relocation := field.NewFilterField("", reflect.Bool.String(), "relocation", true, operator.EQOperator{}, 2)
+
+	tests := []struct {
+		name   string
+		fields []field.IFilterField
+		want   string
+	}{
+		{
+			name:   "Merge single field",
+			fields: []field.IFilterField{remote},
+			want:   `{"remote":{"$eq":true}}`,
+		},
+		{
+			name:   "Merge three fields so that exactly one of them matches",
+			fields: []field.IFilterField{remote, city, relocation},
+			want: `{"$or":[` +
+				`{"$and":[{"remote":{"$eq":true}},{"$nor":[{"city":{"$eq":"Baku"}}]},{"$nor":[{"relocation":{"$eq":true}}]}]},` +
+				`{"$and":[{"$nor":[{"remote":{"$eq":true}}]},{"city":{"$eq":"Baku"}},{"$nor":[{"relocation":{"$eq":true}}]}]},` +
+				`{"$and":[{"$nor":[{"remote":{"$eq":true}}]},{"$nor":[{"city":{"$eq":"Baku"}}]},{"relocation":{"$eq":true}}]}` +
+				`]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extJSON(t, NewXorMergePolicy().Merge(tt.fields...)))
+		})
+	}
+}
+
+func TestCoalesceMergePolicy_Merge(t *testing.T) {
+	newPrice := func(value int, op operator.IOperator) field.IFilterField {
+		return field.NewFilterField("", reflect.Int.String(), "price", value, op, 0)
+	}
+
+	tests := []struct {
+		name   string
+		fields []field.IFilterField
+		want   string
+	}{
+		{
+			name:   "Merge compatible operators into a single sub-document",
+			fields: []field.IFilterField{newPrice(10, operator.GTEOperator{}), newPrice(100, operator.LTOperator{})},
+			want:   `{"price":{"$gte":10,"$lt":100}}`,
+		},
+		{
+			name:   "Merge gt and ne operators into a single sub-document",
+			fields: []field.IFilterField{newPrice(10, operator.GTOperator{}), newPrice(50, operator.NEOperator{})},
+			want:   `{"price":{"$gt":10,"$ne":50}}`,
+		},
+		{
+			name: "Merge repeated operators using and",
+			fields: []field.IFilterField{
+				newPrice(10, operator.NEOperator{}),
+				newPrice(100, operator.LTOperator{}),
+				newPrice(50, operator.NEOperator{}),
+			},
+			want: `{"$and":[{"price":{"$ne":10}},{"price":{"$lt":100}},{"price":{"$ne":50}}]}`,
+		},
+		{
+			name: "Merge outputs of other merge policies using and",
+			fields: []field.IFilterField{
+				newPrice(10, operator.GTOperator{}),
+				field.NewMergedField(
+					[]field.IFilterField{newPrice(20, operator.LTOperator{}), newPrice(80, operator.GTOperator{})},
+					NewOrMergePolicy().Merge(newPrice(20, operator.LTOperator{}), newPrice(80, operator.GTOperator{})),
+				),
+			},
+			want: `{"$and":[{"price":{"$gt":10}},{"$or":[{"price":{"$lt":20}},{"price":{"$gt":80}}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extJSON(t, NewCoalesceMergePolicy().Merge(tt.fields...)))
+		})
+	}
+}
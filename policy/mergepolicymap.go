@@ -0,0 +1,49 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package policy
+
+// IMergePolicyMap is used to look up the merge policy
+// duplicate fields name in their merge tag (e.g. `merge:"or"`).
+type IMergePolicyMap interface {
+	Get(name string) IMergePolicy
+	Set(name string, mergePolicy IMergePolicy) IMergePolicy
+	SetSource(source map[string]IMergePolicy)
+}
+
+type mergePolicyMap struct {
+	source map[string]IMergePolicy
+}
+
+func (m *mergePolicyMap) Get(name string) IMergePolicy {
+	return m.source[name]
+}
+
+func (m *mergePolicyMap) Set(name string, mergePolicy IMergePolicy) IMergePolicy {
+	m.source[name] = mergePolicy
+	return mergePolicy
+}
+
+func (m *mergePolicyMap) SetSource(source map[string]IMergePolicy) {
+	m.source = source
+}
+
+func NewMergePolicyMap() IMergePolicyMap {
+	return &mergePolicyMap{
+		source: map[string]IMergePolicy{
+			"override": NewOverrideMergePolicy(),
+			"and":      NewAndMergePolicy(),
+			"coalesce": NewCoalesceMergePolicy(),
+			"or":       NewOrMergePolicy(),
+			"xor":      NewXorMergePolicy(),
+			"not":      NewNotMergePolicy(),
+			"nor":      NewNorMergePolicy(),
+		},
+	}
+}
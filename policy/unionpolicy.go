@@ -0,0 +1,56 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package policy
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IUnionPolicy is used to run the same built pipeline against several collections
+// and combine their results, e.g. to search live and archived documents
+// sharing the same schema with a single filter struct.
+// It is injected into the IFilterBuilder as a dependency next to the IJoinPolicy
+// and applied to the pipeline once the join and match stages are built.
+type IUnionPolicy interface {
+	// Union returns the pipeline extended with the stages
+	// combining its results with the results of the other collections.
+	Union(pipeline mongo.Pipeline) mongo.Pipeline
+}
+
+// unionWithPolicy combines the results of the pipeline with the results
+// of the same pipeline run against each of the collections using the $unionWith stage.
+type unionWithPolicy struct {
+	method      string
+	collections []string
+}
+
+func (u *unionWithPolicy) getUnionWith(collection string, pipeline mongo.Pipeline) bson.D {
+	return bson.D{
+		{Key: "coll", Value: collection},
+		{Key: "pipeline", Value: pipeline},
+	}
+}
+
+func (u *unionWithPolicy) Union(pipeline mongo.Pipeline) mongo.Pipeline {
+	union := make(mongo.Pipeline, 0, len(pipeline)+len(u.collections))
+	union = append(union, pipeline...)
+	for _, collection := range u.collections {
+		union = append(union, bson.D{{Key: "$unionWith", Value: u.getUnionWith(collection, pipeline)}})
+	}
+	return union
+}
+
+func NewUnionWithPolicy(collections ...string) IUnionPolicy {
+	return &unionWithPolicy{
+		method:      "unionWith",
+		collections: collections,
+	}
+}
@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUnionWithPolicy_Union(t *testing.T) {
+	match := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+	}}}
+
+	tests := []struct {
+		name        string
+		collections []string
+		want        mongo.Pipeline
+	}{
+		{
+			name: "Union without collections keeps the pipeline",
+			want: mongo.Pipeline{match},
+		},
+		{
+			name:        "Union with collections runs the pipeline against each of them",
+			collections: []string{"archivedJobs", "draftJobs"},
+			want: mongo.Pipeline{
+				match,
+				{{Key: "$unionWith", Value: bson.D{
+					{Key: "coll", Value: "archivedJobs"},
+					{Key: "pipeline", Value: mongo.Pipeline{match}},
+				}}},
+				{{Key: "$unionWith", Value: bson.D{
+					{Key: "coll", Value: "draftJobs"},
+					{Key: "pipeline", Value: mongo.Pipeline{match}},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewUnionWithPolicy(tt.collections...).Union(mongo.Pipeline{match})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package projection
+
+import (
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IProjection is used to hold the paths of the fields a query returns
+// (e.g. the fields of a response struct) along with the relations
+// the fields of other collections are joined with.
+// The fields of other collections can only be returned by the pipeline,
+// which joins them, while the find query only returns the fields of its own collection.
+type IProjection interface {
+	// Add adds the path of a field to the projection (relation is nil for the fields of the own collection)
+	Add(path string, relation *field.Relation) IProjection
+
+	// Paths returns the projected paths in the order they were added
+	Paths() []string
+
+	// Relations returns the relations of the projected fields of other collections
+	Relations() []field.Relation
+
+	// Find returns the projection document of the fields of the own collection
+	Find() bson.D
+
+	// Pipeline returns the projection document of all the fields
+	Pipeline() bson.D
+}
+
+type projection struct {
+	paths     []string
+	relations map[string]*field.Relation
+}
+
+// Add adds the path of a field to the projection (relation is nil for the fields of the own collection)
+// Paths which are added more than once are projected once.
+func (p *projection) Add(path string, relation *field.Relation) IProjection {
+	if _, exists := p.relations[path]; !exists {
+		p.paths = append(p.paths, path)
+	}
+	p.relations[path] = relation
+	return p
+}
+
+// Paths returns the projected paths in the order they were added
+func (p *projection) Paths() []string {
+	return p.paths
+}
+
+// Relations returns the relations of the projected fields of other collections
+// in the order their first fields were added (once per join path, see field.Relation.JoinPath)
+func (p *projection) Relations() []field.Relation {
+	var relations []field.Relation
+	added := map[string]bool{}
+	for _, path := range p.paths {
+		relation := p.relations[path]
+		if relation == nil || added[relation.JoinPath()] {
+			continue
+		}
+		added[relation.JoinPath()] = true
+		relations = append(relations, *relation)
+	}
+	return relations
+}
+
+// Find returns the projection document of the fields of the own collection
+func (p *projection) Find() bson.D {
+	document := bson.D{}
+	for _, path := range p.paths {
+		if p.relations[path] == nil {
+			document = append(document, bson.E{Key: path, Value: 1})
+		}
+	}
+	return document
+}
+
+// Pipeline returns the projection document of all the fields
+func (p *projection) Pipeline() bson.D {
+	document := bson.D{}
+	for _, path := range p.paths {
+		document = append(document, bson.E{Key: path, Value: 1})
+	}
+	return document
+}
+
+// NewProjection creates a new empty projection
+func NewProjection() IProjection {
+	return &projection{
+		relations: map[string]*field.Relation{},
+	}
+}
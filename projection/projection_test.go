@@ -0,0 +1,43 @@
+package projection
+
+import (
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestProjection(t *testing.T) {
+	company := field.NewRelation("companies", "companyId", "_id", "company")
+	got := NewProjection().
+		Add("title", nil).
+		Add("company.name", company).
+		Add("company.size", company).
+		Add("title", nil)
+
+	assert.Equal(t, []string{"title", "company.name", "company.size"}, got.Paths())
+	assert.Equal(t, []field.Relation{*company}, got.Relations())
+	assert.Equal(t, bson.D{{Key: "title", Value: 1}}, got.Find())
+	assert.Equal(t, bson.D{
+		{Key: "title", Value: 1},
+		{Key: "company.name", Value: 1},
+		{Key: "company.size", Value: 1},
+	}, got.Pipeline())
+}
+
+func TestProjection_Relations_RelationChain(t *testing.T) {
+	// the chains of the fields are scanned separately, but they are joined at the same path
+	chain := func() *field.Relation {
+		company := field.NewRelation("companies", "companyId", "_id", "company")
+		industry := field.NewRelation("industries", "company.industryId", "_id", "company.industry")
+		industry.Parent = company
+		return industry
+	}
+	industry := chain()
+	got := NewProjection().
+		Add("company.industry.name", industry).
+		Add("company.industry.sector", chain())
+
+	assert.Equal(t, []field.Relation{*industry}, got.Relations())
+}
@@ -0,0 +1,216 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package repository
+
+import (
+	"context"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ICollection is the subset of the methods of *mongo.Collection the repository runs the queries with.
+// *mongo.Collection implements it, and so can a fake collection in the tests.
+type ICollection interface {
+	Find(ctx context.Context, filter interface{},
+		opts ...*options.FindOptions) (*mongo.Cursor, error)
+	FindOne(ctx context.Context, filter interface{},
+		opts ...*options.FindOneOptions) *mongo.SingleResult
+	CountDocuments(ctx context.Context, filter interface{},
+		opts ...*options.CountOptions) (int64, error)
+	Distinct(ctx context.Context, fieldName string, filter interface{},
+		opts ...*options.DistinctOptions) ([]interface{}, error)
+	Aggregate(ctx context.Context, pipeline interface{},
+		opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+}
+
+// IRepository is used to run the queries built from filter structs against a collection
+// and to decode the documents they return into T. Only Aggregate joins the relations,
+// the other queries return error for the filter structs with fields of other collections.
+type IRepository[T any] interface {
+	// Find returns the documents matching the filter struct (sorted, paginated and projected).
+	Find(ctx context.Context, filterStruct interface{}) ([]T, error)
+
+	// FindOne returns the first document matching the filter struct (sorted and projected)
+	// or mongo.ErrNoDocuments if there is none.
+	FindOne(ctx context.Context, filterStruct interface{}) (T, error)
+
+	// Count returns the number of the documents matching the filter struct (regardless of the pagination).
+	Count(ctx context.Context, filterStruct interface{}) (int64, error)
+
+	// Distinct returns the distinct values of the field of the documents matching the filter struct.
+	Distinct(ctx context.Context, fieldName string, filterStruct interface{}) ([]interface{}, error)
+
+	// Aggregate returns the documents returned by the pipeline built from the filter struct.
+	Aggregate(ctx context.Context, filterStruct interface{}) ([]T, error)
+}
+
+type repository[T any] struct {
+	collection ICollection
+	scanner    scanner.IScanner
+	builder    builder.IFilterBuilder
+	paginator  paginator.IPaginator
+}
+
+// build scans the filter struct and builds the filter, extending the base builder
+// of the repository (with its fields, policies and projection) by the scanned fields.
+func (r *repository[T]) build(filterStruct interface{}) (builder.IFilterBuilder, error) {
+	fields, err := r.scanner.Scan(filterStruct, nil, len(r.builder.GetFields()))
+	if err != nil {
+		return nil, errors.Wrap(err, "filter can not be scanned")
+	}
+
+	sort, err := r.scanner.ScanSort(filterStruct)
+	if err != nil {
+		return nil, errors.Wrap(err, "sort can not be scanned")
+	}
+
+	pagination, err := r.scanner.ScanPagination(filterStruct, r.paginator)
+	if err != nil {
+		return nil, errors.Wrap(err, "pagination can not be scanned")
+	}
+
+	filter := r.builder.AddFields(fields).SetSort(sort).SetPagination(pagination).
+		MergeDuplicateFields().Build()
+	if filter.Err() != nil {
+		return nil, errors.Wrap(filter.Err(), "filter can not be built")
+	}
+	return filter, nil
+}
+
+// buildQuery builds the filter the same way as build for the queries which do not join the relations
+// (find, count and distinct). It returns error if the filter has fields of other collections,
+// since they would be matched against the documents of the collection itself.
+func (r *repository[T]) buildQuery(filterStruct interface{}) (builder.IFilterBuilder, error) {
+	filter, err := r.build(filterStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fld := range filter.GetFields() {
+		if relation := fld.GetRelation(); relation != nil {
+			return nil, errors.Errorf("field %s of the relation %s can only be filtered by using Aggregate",
+				fld.GetName(), relation.As)
+		}
+	}
+	return filter, nil
+}
+
+// Find returns the documents matching the filter struct (sorted, paginated and projected).
+// The find query does not join the relations, use Aggregate to filter by the fields of other collections
+// (Find returns error for such a filter).
+func (r *repository[T]) Find(ctx context.Context, filterStruct interface{}) ([]T, error) {
+	filter, err := r.buildQuery(filterStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, filter.Output(), filter.FindOptions())
+	if err != nil {
+		return nil, errors.Wrap(err, "find failed")
+	}
+	return decode[T](ctx, cursor)
+}
+
+// FindOne returns the first document matching the filter struct (sorted and projected)
+// or mongo.ErrNoDocuments if there is none. The page is skipped the same way as by Find
+// and it returns error for the filters with fields of other collections the same way as Find.
+func (r *repository[T]) FindOne(ctx context.Context, filterStruct interface{}) (T, error) {
+	var document T
+	filter, err := r.buildQuery(filterStruct)
+	if err != nil {
+		return document, err
+	}
+
+	findOptions := filter.FindOptions()
+	findOneOptions := options.FindOne()
+	findOneOptions.Sort = findOptions.Sort
+	findOneOptions.Skip = findOptions.Skip
+	findOneOptions.Projection = findOptions.Projection
+
+	if err := r.collection.FindOne(ctx, filter.Output(), findOneOptions).Decode(&document); err != nil {
+		return document, errors.Wrap(err, "find one failed")
+	}
+	return document, nil
+}
+
+// Count returns the number of the documents matching the filter struct (regardless of the pagination),
+// e.g. the total number of the documents of the pages returned by Find.
+// It returns error for the filters with fields of other collections the same way as Find.
+func (r *repository[T]) Count(ctx context.Context, filterStruct interface{}) (int64, error) {
+	filter, err := r.buildQuery(filterStruct)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter.Output())
+	if err != nil {
+		return 0, errors.Wrap(err, "count failed")
+	}
+	return count, nil
+}
+
+// Distinct returns the distinct values of the field of the documents matching the filter struct.
+// It returns error for the filters with fields of other collections the same way as Find.
+func (r *repository[T]) Distinct(ctx context.Context, fieldName string,
+	filterStruct interface{}) ([]interface{}, error) {
+	filter, err := r.buildQuery(filterStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := r.collection.Distinct(ctx, fieldName, filter.Output())
+	if err != nil {
+		return nil, errors.Wrap(err, "distinct failed")
+	}
+	return values, nil
+}
+
+// Aggregate returns the documents returned by the pipeline built from the filter struct,
+// which joins the relations, so that the documents can be filtered by the fields of other collections.
+func (r *repository[T]) Aggregate(ctx context.Context, filterStruct interface{}) ([]T, error) {
+	filter, err := r.build(filterStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, filter.Pipeline())
+	if err != nil {
+		return nil, errors.Wrap(err, "aggregate failed")
+	}
+	return decode[T](ctx, cursor)
+}
+
+// decode decodes all the documents of the cursor into T and closes it
+func decode[T any](ctx context.Context, cursor *mongo.Cursor) ([]T, error) {
+	documents := []T{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, errors.Wrap(err, "documents can not be decoded")
+	}
+	return documents, nil
+}
+
+// NewRepository creates a new repository of the collection.
+// The filter structs are scanned by the scanner and paginated by the paginator.
+// The filters extend the provided builder, so that the fields shared by all the queries
+// (e.g. tenant + not deleted), the policies and the projection can be set on it once.
+func NewRepository[T any](collection ICollection, scanner scanner.IScanner,
+	builder builder.IFilterBuilder, paginator paginator.IPaginator) IRepository[T] {
+	return &repository[T]{
+		collection: collection,
+		scanner:    scanner,
+		builder:    builder,
+		paginator:  paginator,
+	}
+}
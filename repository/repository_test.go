@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
+	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/scanner"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// *mongo.Collection is the collection the repository is used with
+var _ ICollection = (*mongo.Collection)(nil)
+
+type Job struct {
+	Title  string `bson:"title"`
+	Salary int    `bson:"salary"`
+}
+
+type CompanyFilter struct {
+	Name *string `filter:"name" operator:"eq"`
+}
+
+type JobFilter struct {
+	Title    *string        `filter:"title" operator:"eq"`
+	Salary   *int           `filter:"salary" operator:"gte"`
+	Company  *CompanyFilter `relation:"companies,local=companyId,foreign=_id,as=company"`
+	Sort     []string       `filter:"sort"`
+	Page     int64          `filter:"page"`
+	PageSize int64          `filter:"pageSize"`
+}
+
+// fakeCollection records the queries it is called with and returns the provided documents
+type fakeCollection struct {
+	documents []interface{}
+	err       error
+
+	filter         interface{}
+	pipeline       interface{}
+	findOptions    *options.FindOptions
+	findOneOptions *options.FindOneOptions
+	fieldName      string
+}
+
+func (c *fakeCollection) Find(ctx context.Context, filter interface{},
+	opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	c.filter, c.findOptions = filter, opts[0]
+	if c.err != nil {
+		return nil, c.err
+	}
+	return mongo.NewCursorFromDocuments(c.documents, nil, nil)
+}
+
+func (c *fakeCollection) FindOne(ctx context.Context, filter interface{},
+	opts ...*options.FindOneOptions) *mongo.SingleResult {
+	c.filter, c.findOneOptions = filter, opts[0]
+	if len(c.documents) == 0 {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+	return mongo.NewSingleResultFromDocument(c.documents[0], c.err, nil)
+}
+
+func (c *fakeCollection) CountDocuments(ctx context.Context, filter interface{},
+	opts ...*options.CountOptions) (int64, error) {
+	c.filter = filter
+	return int64(len(c.documents)), c.err
+}
+
+func (c *fakeCollection) Distinct(ctx context.Context, fieldName string, filter interface{},
+	opts ...*options.DistinctOptions) ([]interface{}, error) {
+	c.filter, c.fieldName = filter, fieldName
+	return c.documents, c.err
+}
+
+func (c *fakeCollection) Aggregate(ctx context.Context, pipeline interface{},
+	opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	c.pipeline = pipeline
+	if c.err != nil {
+		return nil, c.err
+	}
+	return mongo.NewCursorFromDocuments(c.documents, nil, nil)
+}
+
+func newRepository(collection ICollection) IRepository[Job] {
+	return NewRepository[Job](collection,
+		scanner.NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "relation"),
+		builder.NewFilterBuilder(), paginator.NewPaginator(10, 100))
+}
+
+var golang = "golang"
+
+// joined is a filter with a field of the joined company, which can only be matched by the pipeline
+var joined = JobFilter{Title: &golang, Company: &CompanyFilter{Name: &golang}}
+
+func TestRepository_Find(t *testing.T) {
+	salary := 1000
+	tests := []struct {
+		name        string
+		filter      JobFilter
+		documents   []interface{}
+		err         error
+		want        []Job
+		wantFilter  bson.D
+		wantOptions *options.FindOptions
+		wantErr     bool
+	}{
+		{
+			name:      "Documents are decoded",
+			filter:    JobFilter{Title: &golang},
+			documents: []interface{}{bson.D{{Key: "title", Value: "golang"}, {Key: "salary", Value: 2000}}},
+			want:      []Job{{Title: "golang", Salary: 2000}},
+			wantFilter: bson.D{
+				{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}},
+			},
+			wantOptions: options.Find().SetLimit(10),
+		},
+		{
+			name:      "Sort and pagination are passed as options",
+			filter:    JobFilter{Salary: &salary, Sort: []string{"-salary"}, Page: 3, PageSize: 20},
+			documents: []interface{}{},
+			want:      []Job{},
+			wantFilter: bson.D{
+				{Key: "salary", Value: bson.D{{Key: "$gte", Value: 1000}}},
+			},
+			wantOptions: options.Find().SetSort(bson.D{{Key: "salary", Value: -1}}).SetSkip(40).SetLimit(20),
+		},
+		{
+			name:    "Invalid sort is reported before querying",
+			filter:  JobFilter{Sort: []string{"unknown"}},
+			wantErr: true,
+		},
+		{
+			name:    "Fields of relations are reported before querying",
+			filter:  joined,
+			wantErr: true,
+		},
+		{
+			name:       "Query error is returned",
+			filter:     JobFilter{Title: &golang},
+			err:        errors.New("connection refused"),
+			wantFilter: bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := &fakeCollection{documents: tt.documents, err: tt.err}
+			got, err := newRepository(collection).Find(context.Background(), tt.filter)
+
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+			if tt.wantFilter != nil {
+				assert.Equal(t, tt.wantFilter, collection.filter)
+			}
+			if tt.wantOptions != nil {
+				assert.Equal(t, tt.wantOptions, collection.findOptions)
+			}
+		})
+	}
+}
+
+func TestRepository_FindOne(t *testing.T) {
+	collection := &fakeCollection{documents: []interface{}{bson.D{{Key: "title", Value: "golang"}}}}
+	got, err := newRepository(collection).FindOne(context.Background(), JobFilter{Sort: []string{"title"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Job{Title: "golang"}, got)
+	assert.Equal(t, bson.D{{Key: "title", Value: 1}}, collection.findOneOptions.Sort)
+
+	_, err = newRepository(&fakeCollection{}).FindOne(context.Background(), JobFilter{})
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	// the fields of relations are reported before querying
+	collection = &fakeCollection{documents: []interface{}{bson.D{{Key: "title", Value: "golang"}}}}
+	_, err = newRepository(collection).FindOne(context.Background(), joined)
+	assert.Error(t, err)
+	assert.Nil(t, collection.filter)
+}
+
+func TestRepository_Count(t *testing.T) {
+	collection := &fakeCollection{documents: []interface{}{bson.D{}, bson.D{}}}
+	got, err := newRepository(collection).Count(context.Background(), JobFilter{Title: &golang, Page: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), got)
+	assert.Equal(t, bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}}, collection.filter)
+
+	// the fields of relations are reported before querying
+	collection = &fakeCollection{documents: []interface{}{bson.D{}}}
+	_, err = newRepository(collection).Count(context.Background(), joined)
+	assert.Error(t, err)
+	assert.Nil(t, collection.filter)
+}
+
+func TestRepository_Distinct(t *testing.T) {
+	collection := &fakeCollection{documents: []interface{}{"golang", "python"}}
+	got, err := newRepository(collection).Distinct(context.Background(), "title", JobFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"golang", "python"}, got)
+	assert.Equal(t, "title", collection.fieldName)
+	assert.Equal(t, bson.D{}, collection.filter)
+
+	// the fields of relations are reported before querying
+	collection = &fakeCollection{documents: []interface{}{"golang"}}
+	_, err = newRepository(collection).Distinct(context.Background(), "title", joined)
+	assert.Error(t, err)
+	assert.Nil(t, collection.filter)
+}
+
+func TestRepository_Aggregate(t *testing.T) {
+	collection := &fakeCollection{documents: []interface{}{bson.D{{Key: "title", Value: "golang"}}}}
+	got, err := newRepository(collection).Aggregate(context.Background(), JobFilter{Title: &golang})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Job{{Title: "golang"}}, got)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}}}},
+		{{Key: "$limit", Value: int64(10)}},
+	}, collection.pipeline)
+}
+
+func TestRepository_Aggregate_Relations(t *testing.T) {
+	collection := &fakeCollection{documents: []interface{}{bson.D{{Key: "title", Value: "golang"}}}}
+	got, err := newRepository(collection).Aggregate(context.Background(), joined)
+
+	// the pipeline joins the relations, so their fields can be filtered by
+	assert.NoError(t, err)
+	assert.Equal(t, []Job{{Title: "golang"}}, got)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "title", Value: bson.D{{Key: "$eq", Value: "golang"}}}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "companies",
+			"localField":   "companyId",
+			"foreignField": "_id",
+			"as":           "company",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$company",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "company.name", Value: bson.D{{Key: "$eq", Value: "golang"}}}}}},
+		{{Key: "$limit", Value: int64(10)}},
+	}, collection.pipeline)
+}
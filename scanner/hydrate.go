@@ -0,0 +1,143 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package scanner
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jobsearch-demos/mongo-filter-struct/field"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Hydrate populates the provided struct (a pointer) from the filter previously built
+// from the fields of a struct of the same type, so that a stored filter can be turned back
+// into the struct it was built from (e.g. to fill in a search form).
+// Every condition of the filter is mapped to the struct field with the same lookup name
+// (nested the same way as by Scan) and operator, and the conditions combined by $or or $nor
+// are only mapped to the fields of the groups (or merge methods) combining them the same way.
+// The fields merged by the xor or not merge methods are not supported: the conditions
+// their merge policies produce are not mapped back to them and are reported as unmapped.
+// The parts of the filter which can not be mapped are reported by the error,
+// while the struct is populated by the parts which can.
+func (s *scanner) Hydrate(filter bson.D, filterStruct interface{}) error {
+	rv := reflect.ValueOf(filterStruct)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("filterStruct has to be a pointer to a struct")
+	}
+
+	targets, err := s.targets(scanContext{groups: map[groupKey]*field.Group{}}, rv.Elem().Type(), nil)
+	if err != nil {
+		return err
+	}
+
+	var unmapped []string
+	s.hydrate(filter, "", "", targets, rv.Elem(), &unmapped)
+	if len(unmapped) > 0 {
+		return errors.Errorf("filter parts could not be mapped: %s", strings.Join(unmapped, ", "))
+	}
+	return nil
+}
+
+// hydrate maps the parts of the filter combined by the logical operator (if any) to the targets
+// and sets the struct fields to their values. The path is the position of the filter
+// in the whole one, and the parts which can not be mapped are appended to unmapped.
+func (s *scanner) hydrate(filter bson.D, path string, logicalOperator string,
+	targets []*target, root reflect.Value, unmapped *[]string) {
+	for _, element := range filter {
+		elementPath := path + element.Key
+
+		switch element.Key {
+		case "$and", "$or", "$nor":
+			nestedLogic := logicalOperator
+			if element.Key != "$and" {
+				nestedLogic = strings.TrimPrefix(element.Key, "$")
+			}
+
+			documents, ok := element.Value.(bson.A)
+			if !ok {
+				*unmapped = append(*unmapped, elementPath)
+				continue
+			}
+			for i, document := range documents {
+				nestedFilter, ok := document.(bson.D)
+				if !ok {
+					*unmapped = append(*unmapped, elementPath+"."+strconv.Itoa(i))
+					continue
+				}
+				s.hydrate(nestedFilter, elementPath+"."+strconv.Itoa(i)+".", nestedLogic, targets, root, unmapped)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(element.Key, "$") {
+			*unmapped = append(*unmapped, elementPath)
+			continue
+		}
+
+		// a field is either compared by operators or equal to the value
+		operators, ok := element.Value.(bson.D)
+		if !ok || len(operators) == 0 || !strings.HasPrefix(operators[0].Key, "$") {
+			operators = bson.D{{Key: "$eq", Value: element.Value}}
+		}
+
+		for _, op := range operators {
+			t := find(targets, element.Key, strings.TrimPrefix(op.Key, "$"), logicalOperator)
+			if t == nil || !set(root, t.index, op.Value) {
+				*unmapped = append(*unmapped, elementPath+"."+op.Key)
+				continue
+			}
+			t.mapped = true
+		}
+	}
+}
+
+// find returns the target with the path, the operator and the logical operator which is not mapped yet
+func find(targets []*target, path string, operator string, logicalOperator string) *target {
+	for _, t := range targets {
+		if !t.mapped && t.path == path && t.operator.ExternalName() == operator && t.logic == logicalOperator {
+			return t
+		}
+	}
+	return nil
+}
+
+// set sets the struct field at the index sequence to the value converted to its type
+// (allocating the nil pointers to the structs it is nested in) and reports whether it can be set
+func set(root reflect.Value, index []int, value interface{}) bool {
+	fieldType := root.Type().FieldByIndex(index).Type
+
+	raw, err := bson.Marshal(bson.D{{Key: "value", Value: value}})
+	if err != nil {
+		return false
+	}
+	converted := reflect.New(fieldType)
+	if err := bson.Raw(raw).Lookup("value").Unmarshal(converted.Interface()); err != nil {
+		return false
+	}
+
+	current := root
+	for i, fieldIndex := range index {
+		current = current.Field(fieldIndex)
+		if i == len(index)-1 {
+			break
+		}
+		if current.Kind() == reflect.Ptr {
+			if current.IsNil() {
+				current.Set(reflect.New(current.Type().Elem()))
+			}
+			current = current.Elem()
+		}
+	}
+	current.Set(converted.Elem())
+	return true
+}
@@ -12,9 +12,15 @@ package scanner
 import (
 	"github.com/jobsearch-demos/mongo-filter-struct/field"
 	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
+	"github.com/jobsearch-demos/mongo-filter-struct/projection"
+	"github.com/jobsearch-demos/mongo-filter-struct/sorter"
 	"github.com/jobsearch-demos/mongo-filter-struct/validator"
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"reflect"
+	"strings"
 )
 
 // IScanner is used to scan struct and find fields with tags
@@ -23,13 +29,39 @@ import (
 // It is used to find field name, operator and value
 type IScanner interface {
 	// makeField creates a new filter field from provided struct field
-	makeField(collection string, reflectionValue reflect.Value,
-		reflectionType reflect.StructField,
-		parentField *reflect.StructField, index int) (field.IFilterField, error)
+	makeField(context scanContext, reflectionValue reflect.Value,
+		reflectionType reflect.StructField, index int) (field.IFilterField, error)
 
 	// Scan scans the provided field and returns a list of IFilterField
 	Scan(filterStruct interface{},
 		parentField *reflect.StructField, index int) ([]field.IFilterField, error)
+
+	// ScanSort scans the sort specification of the provided struct and returns the sort document
+	ScanSort(filterStruct interface{}) (bson.D, error)
+
+	// SetSortName sets the lookup name of the field holding the sort specification.
+	SetSortName(sortName string) IScanner
+
+	// ScanPagination scans the pagination fields of the provided struct and returns the pagination
+	ScanPagination(filterStruct interface{}, pager paginator.IPaginator) (paginator.Pagination, error)
+
+	// SetPaginationNames sets the lookup names of the fields holding the pagination.
+	SetPaginationNames(pageName string, pageSizeName string, limitName string, offsetName string) IScanner
+
+	// ScanProjection scans the fields of the provided (response) struct and returns their projection
+	ScanProjection(responseStruct interface{}, parentField *reflect.StructField) (projection.IProjection, error)
+
+	// ScanSchema returns a field holding the zero value of its type for every filterable field of the provided struct
+	ScanSchema(filterStruct interface{}) ([]field.IFilterField, error)
+
+	// Hydrate populates the provided struct (a pointer) from the filter previously built from a struct of its type
+	Hydrate(filter bson.D, filterStruct interface{}) error
+
+	// SetMergeTagName sets the name of the tag duplicate fields name their merge policy in.
+	SetMergeTagName(mergeTagName string) IScanner
+
+	// SetGroupTagName sets the name of the tag fields name their logical group in.
+	SetGroupTagName(groupTagName string) IScanner
 }
 
 type scanner struct {
@@ -38,15 +70,538 @@ type scanner struct {
 	lookupTagName   string
 	operatorTagName string
 	relationTagName string
+	mergeTagName    string
+	groupTagName    string
+	sortName        string
+	pageName        string
+	pageSizeName    string
+	limitName       string
+	offsetName      string
+}
+
+// groupKey identifies a group by its name among the groups of the same parent
+type groupKey struct {
+	parent *field.Group
+	name   string
+}
+
+// scanContext holds the state the fields of a (nested) struct are scanned with
+type scanContext struct {
+	// collection is the collection the fields are in
+	collection string
+	// path is prepended to the lookup names of the fields
+	path string
+	// relation is the relation the struct is joined with, if any
+	relation *field.Relation
+	// group is the logical group the fields belong to, if any
+	group *field.Group
+	// nested is true for the fields of nested structs
+	nested bool
+	// groups holds all the groups of the scanned struct, so that the
+	// fields naming the same group end up in the same one
+	groups map[groupKey]*field.Group
+}
+
+// SetMergeTagName sets the name of the tag duplicate fields name their merge policy in.
+func (s *scanner) SetMergeTagName(mergeTagName string) IScanner {
+	s.mergeTagName = mergeTagName
+	return s
+}
+
+// SetGroupTagName sets the name of the tag fields name their logical group in.
+func (s *scanner) SetGroupTagName(groupTagName string) IScanner {
+	s.groupTagName = groupTagName
+	return s
+}
+
+// SetSortName sets the lookup name of the field holding the sort specification.
+func (s *scanner) SetSortName(sortName string) IScanner {
+	s.sortName = sortName
+	return s
+}
+
+// SetPaginationNames sets the lookup names of the fields holding the pagination.
+func (s *scanner) SetPaginationNames(pageName string, pageSizeName string,
+	limitName string, offsetName string) IScanner {
+	s.pageName, s.pageSizeName, s.limitName, s.offsetName = pageName, pageSizeName, limitName, offsetName
+	return s
 }
 
 // Scan scans the provided field and returns a list of IFilterField
 // It does not do anything other than scanning the struct and creating a list of IFilterField
 // It is responsible for checking the type of the fields and creating respective IFilterField.
+// The sort specification of the struct is not a filter field, but it is validated as well,
+// so that invalid sort keys are reported the same way as invalid filters.
 func (s *scanner) Scan(filterStruct interface{},
 	parentField *reflect.StructField, index int) ([]field.IFilterField, error) {
-	collection := ""
+	context := scanContext{groups: map[groupKey]*field.Group{}}
+
+	// if there is a parent field, the lookup names
+	// of the scanned fields are nested under its own
+	if parentField != nil {
+		context.path = s.lookupName(*parentField) + "."
+	}
+
+	fields, err := s.scan(context, filterStruct, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ScanSort(filterStruct); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ScanSort scans the sort specification of the provided struct and returns the sort document.
+// The specification is held by the top-level field with the sort lookup name (`sort` by default),
+// either a string (e.g. "-postedAt,title") or a list of strings, and only the lookup names
+// of the fields of the struct (set or not) can be sorted by.
+func (s *scanner) ScanSort(filterStruct interface{}) (bson.D, error) {
+	rv, rt := reflect.ValueOf(filterStruct), reflect.TypeOf(filterStruct)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+		rt = rt.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Errorf("filterStruct has to be a struct")
+	}
+
+	var specification []string
+	for i := 0; i < rv.NumField(); i++ {
+		fieldValue, fieldType := rv.Field(i), rt.Field(i)
+		if s.lookupName(fieldType) != s.sortName || !s.reserved(scanContext{}, fieldType) {
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+
+		switch {
+		case fieldValue.Kind() == reflect.String:
+			specification = append(specification, fieldValue.String())
+		case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < fieldValue.Len(); j++ {
+				specification = append(specification, fieldValue.Index(j).String())
+			}
+		default:
+			return nil, errors.Errorf("sort field %s has to be a string or a list of strings", fieldType.Name)
+		}
+	}
+
+	if len(specification) == 0 {
+		return bson.D{}, nil
+	}
+
+	sortable, err := s.sortable(scanContext{groups: map[groupKey]*field.Group{}}, rt)
+	if err != nil {
+		return nil, err
+	}
+	return sorter.NewSorter(sortable).Sort(specification...)
+}
+
+// ScanSchema returns a field for every filterable field of the provided struct, set or not,
+// holding the zero value of its type (the element type of pointers) along with its lookup name
+// (nested the same way as by Scan), operator, collection, relation, group and merge method.
+// It describes the fields a filter of the struct can consist of, e.g. to validate
+// and type the fields of the queries parsed from text.
+func (s *scanner) ScanSchema(filterStruct interface{}) ([]field.IFilterField, error) {
+	rt := reflect.TypeOf(filterStruct)
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, errors.Errorf("filterStruct has to be a struct")
+	}
+
+	targets, err := s.targets(scanContext{groups: map[groupKey]*field.Group{}}, rt, nil)
+	if err != nil {
+		return nil, err
+	}
 
+	schema := make([]field.IFilterField, 0, len(targets))
+	for i, t := range targets {
+		schemaField := field.NewFilterField(t.collection, t.fieldType.Kind().String(),
+			t.path, reflect.Zero(t.fieldType).Interface(), t.operator, i)
+		if t.relation != nil {
+			schemaField.SetRelation(t.relation)
+		}
+		if t.group != nil {
+			schemaField.SetGroup(t.group)
+		}
+		if t.mergeMethod != "" {
+			schemaField.SetMergeMethod(t.mergeMethod)
+		}
+		schema = append(schema, schemaField)
+	}
+	return schema, nil
+}
+
+// ScanPagination scans the pagination fields of the provided struct and returns the pagination.
+// The pagination is held by the top-level integer fields with the page and page size lookup names
+// (`page` and `pageSize` by default) or the limit and offset lookup names (`limit` and `offset` by default).
+// Unset fields (nil or zero) are left to the defaults of the paginator.
+func (s *scanner) ScanPagination(filterStruct interface{},
+	pager paginator.IPaginator) (paginator.Pagination, error) {
+	rv, rt := reflect.ValueOf(filterStruct), reflect.TypeOf(filterStruct)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+		rt = rt.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return paginator.Pagination{}, errors.Errorf("filterStruct has to be a struct")
+	}
+
+	values := map[string]int64{}
+	for i := 0; i < rv.NumField(); i++ {
+		fieldValue, fieldType := rv.Field(i), rt.Field(i)
+		name := s.lookupName(fieldType)
+		if name == s.sortName || !s.reserved(scanContext{}, fieldType) {
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+
+		switch fieldValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			values[name] = fieldValue.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			values[name] = int64(fieldValue.Uint())
+		default:
+			return paginator.Pagination{}, errors.Errorf("pagination field %s has to be an integer", fieldType.Name)
+		}
+	}
+
+	paged := values[s.pageName] != 0 || values[s.pageSizeName] != 0
+	if paged && (values[s.limitName] != 0 || values[s.offsetName] != 0) {
+		return paginator.Pagination{}, errors.Errorf("pagination can not be set by both %s/%s and %s/%s",
+			s.pageName, s.pageSizeName, s.limitName, s.offsetName)
+	}
+
+	if paged {
+		return pager.Page(values[s.pageName], values[s.pageSizeName])
+	}
+	return pager.Offset(values[s.limitName], values[s.offsetName])
+}
+
+// ScanProjection scans the fields of the provided (response) struct and returns their projection.
+// The fields are traversed the same way as by Scan: the paths of the fields of nested structs
+// are nested under the name of the struct field (or under the parent field, if there is one)
+// and the fields of relation structs are nested under the key the joined document is stored at.
+// Unlike Scan, the fields do not have to be set and the name of a field is taken from its lookup tag,
+// its bson tag or its name, in this order, so that plain response structs can be projected as well.
+func (s *scanner) ScanProjection(responseStruct interface{},
+	parentField *reflect.StructField) (projection.IProjection, error) {
+	rt := reflect.TypeOf(responseStruct)
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, errors.Errorf("responseStruct has to be a struct")
+	}
+
+	context := scanContext{groups: map[groupKey]*field.Group{}}
+	if parentField != nil {
+		name, _ := s.projectionName(*parentField)
+		context.path = name + "."
+	}
+
+	result := projection.NewProjection()
+	if err := s.project(context, rt, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// project adds the paths of the fields of the struct type to the projection
+func (s *scanner) project(context scanContext, structType reflect.Type, result projection.IProjection) error {
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		name, projected := s.projectionName(fieldType)
+		if !projected {
+			continue
+		}
+
+		kind := fieldType.Type
+		if kind.Kind() == reflect.Ptr {
+			kind = kind.Elem()
+		}
+		nested := nestedStruct(kind)
+
+		path, relation := context.path, context.relation
+		if nested && inline(fieldType) {
+			// the fields of inline structs are stored in the document of the struct they are inlined into
+			if err := s.project(context, kind, result); err != nil {
+				return err
+			}
+			continue
+		}
+		if relationTagValue := fieldType.Tag.Get(s.relationTagName); relationTagValue != "" {
+			var err error
+			relation, err = s.makeRelation(relationTagValue, name, context.relation)
+			if err != nil {
+				return err
+			}
+			path = relation.As + "."
+		} else if nested && fieldType.Tag.Get(s.groupTagName) == "" {
+			path = path + name + "."
+		}
+
+		// the structs stored as a single value (e.g. time.Time) are projected as a single field
+		if !nested {
+			result.Add(path+name, relation)
+			continue
+		}
+
+		nestedContext := context
+		nestedContext.path, nestedContext.relation, nestedContext.nested = path, relation, true
+		if err := s.project(nestedContext, kind, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectionName returns the name of the struct field in the document: its lookup tag value,
+// its bson tag value or its lowercased name (the key the driver stores it under by default), in this order.
+// It returns false for the fields which are not stored in the document
+// (unexported fields and the fields with the `-` bson tag).
+func (s *scanner) projectionName(reflectionType reflect.StructField) (string, bool) {
+	if reflectionType.PkgPath != "" {
+		return "", false
+	}
+	if lookupTagValue := reflectionType.Tag.Get(s.lookupTagName); lookupTagValue != "" {
+		return lookupTagValue, true
+	}
+
+	bsonTagValue, _, _ := strings.Cut(reflectionType.Tag.Get("bson"), ",")
+	switch bsonTagValue {
+	case "-":
+		return "", false
+	case "":
+		return strings.ToLower(reflectionType.Name), true
+	}
+	return bsonTagValue, true
+}
+
+// inline checks whether the struct field has the inline bson tag option
+func inline(reflectionType reflect.StructField) bool {
+	_, options, _ := strings.Cut(reflectionType.Tag.Get("bson"), ",")
+	for _, option := range strings.Split(options, ",") {
+		if option == "inline" {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedStruct checks whether the type is a struct whose fields are scanned one by one,
+// i.e. not a struct stored as a single value: one without exported fields (e.g. time.Time)
+// or one of the bson primitive types (e.g. primitive.Timestamp)
+func nestedStruct(structType reflect.Type) bool {
+	return structType.Kind() == reflect.Struct && hasExportedFields(structType) &&
+		structType.PkgPath() != reflect.TypeOf(primitive.Timestamp{}).PkgPath()
+}
+
+// hasExportedFields checks whether the struct type has any exported fields
+func hasExportedFields(structType reflect.Type) bool {
+	for i := 0; i < structType.NumField(); i++ {
+		if structType.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// reserved checks whether the top-level struct field holds the sort specification
+// or the pagination instead of a filter. The fields with an operator tag are filters
+// (or updates) even if their lookup names are the sort or pagination ones.
+func (s *scanner) reserved(context scanContext, reflectionType reflect.StructField) bool {
+	if context.nested || reflectionType.Tag.Get(s.operatorTagName) != "" {
+		return false
+	}
+
+	switch s.lookupName(reflectionType) {
+	case s.sortName, s.pageName, s.pageSizeName, s.limitName, s.offsetName:
+		return true
+	}
+	return false
+}
+
+// sortable returns the lookup names of all the fields of the struct type,
+// nested the same way as the lookup names of the scanned filter fields.
+func (s *scanner) sortable(context scanContext, structType reflect.Type) ([]string, error) {
+	var names []string
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		if s.reserved(context, fieldType) {
+			continue
+		}
+
+		kind := fieldType.Type
+		if kind.Kind() == reflect.Ptr {
+			kind = kind.Elem()
+		}
+
+		// the structs stored as a single value (e.g. time.Time) are sorted by as a single field
+		if nestedStruct(kind) {
+			nested, err := s.nestedContext(context, fieldType)
+			if err != nil {
+				return nil, err
+			}
+
+			nestedNames, err := s.sortable(nested, kind)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, nestedNames...)
+			continue
+		}
+
+		path := context.path
+		if relationTagValue := fieldType.Tag.Get(s.relationTagName); relationTagValue != "" {
+			relation, err := s.makeRelation(relationTagValue, s.lookupName(fieldType), context.relation)
+			if err != nil {
+				return nil, err
+			}
+			path = relation.As + "."
+		}
+		names = append(names, path+s.lookupName(fieldType))
+	}
+	return names, nil
+}
+
+// target is a filterable struct field (set or not), e.g. a struct field a part of a filter can be mapped back to
+type target struct {
+	// path is the lookup name of the field, nested the same way as by Scan
+	path string
+	// operator is the operator of the field
+	operator operator.IOperator
+	// collection is the collection the field is in
+	collection string
+	// relation is the relation the field is joined with, if any
+	relation *field.Relation
+	// group is the logical group the field belongs to, if any
+	group *field.Group
+	// mergeMethod is the merge method named by the merge tag of the field, if any
+	mergeMethod string
+	// fieldType is the type of the field (the element type of pointers)
+	fieldType reflect.Type
+	// logic is the logical operator (e.g. or) the field is combined with the others by, if any
+	logic string
+	// index is the index sequence of the field in the struct (see reflect.Value.FieldByIndex)
+	index []int
+	// mapped is true once a part of the filter is mapped to the field
+	mapped bool
+}
+
+// targets returns the filterable struct fields of the struct type (set or not),
+// traversed the same way as by scan
+func (s *scanner) targets(context scanContext, structType reflect.Type, index []int) ([]*target, error) {
+	// get collection name from the struct using CollectionName method
+	if collectionGetter, exists := structType.MethodByName("CollectionName"); exists {
+		context.collection = collectionGetter.Func.Call([]reflect.Value{reflect.Zero(structType)})[0].String()
+	}
+
+	var targets []*target
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		if s.reserved(context, fieldType) || fieldType.PkgPath != "" {
+			continue
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		kind := fieldType.Type
+		if kind.Kind() == reflect.Ptr {
+			kind = kind.Elem()
+		}
+
+		if nestedStruct(kind) {
+			nested, err := s.nestedContext(context, fieldType)
+			if err != nil {
+				return nil, err
+			}
+
+			nestedTargets, err := s.targets(nested, kind, fieldIndex)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, nestedTargets...)
+			continue
+		}
+
+		collection, path, relation, group := context.collection, context.path, context.relation, context.group
+		if relationTagValue := fieldType.Tag.Get(s.relationTagName); relationTagValue != "" {
+			var err error
+			relation, err = s.makeRelation(relationTagValue, s.lookupName(fieldType), context.relation)
+			if err != nil {
+				return nil, err
+			}
+			collection, path = relation.Collection, relation.As+"."
+		}
+		if groupTagValue := fieldType.Tag.Get(s.groupTagName); groupTagValue != "" {
+			var err error
+			if group, err = s.makeGroup(context, groupTagValue); err != nil {
+				return nil, err
+			}
+		}
+
+		operatorTagValue := fieldType.Tag.Get(s.operatorTagName)
+		op := s.operatorMap.Get(operatorTagValue)
+		if op == nil {
+			return nil, errors.Errorf("operator %s is not supported", operatorTagValue)
+		}
+
+		mergeMethod := fieldType.Tag.Get(s.mergeTagName)
+		targets = append(targets, &target{
+			path:        path + s.lookupName(fieldType),
+			operator:    op,
+			collection:  collection,
+			relation:    relation,
+			group:       group,
+			mergeMethod: mergeMethod,
+			fieldType:   kind,
+			logic:       logic(group, mergeMethod),
+			index:       fieldIndex,
+		})
+	}
+	return targets, nil
+}
+
+// logic returns the logical operator the field is combined with the others by:
+// the method of the closest of its groups which is not combined with and,
+// or its merge method if it is not in such a group (the conjunctive methods yield no operator)
+func logic(group *field.Group, mergeMethod string) string {
+	for ; group != nil; group = group.Parent {
+		if group.Method != "" && group.Method != "and" {
+			return group.Method
+		}
+	}
+
+	switch mergeMethod {
+	case "", "and", "coalesce", "override":
+		return ""
+	}
+	return mergeMethod
+}
+
+// scan scans the provided struct the same way as Scan does.
+// Nested structs without their own CollectionName method
+// are considered to be in the collection of the struct they are nested into.
+func (s *scanner) scan(context scanContext, filterStruct interface{}, index int) ([]field.IFilterField, error) {
 	// prepare the list of fields to return
 	var filterFields []field.IFilterField
 
@@ -70,7 +625,7 @@ func (s *scanner) Scan(filterStruct interface{},
 
 	// if the struct has CollectionName method, get the collection name
 	if exists {
-		collection = collectionGetter.Func.Call([]reflect.Value{rv})[0].String()
+		context.collection = collectionGetter.Func.Call([]reflect.Value{rv})[0].String()
 	}
 
 	// iterate over the fields of the provided struct
@@ -79,14 +634,32 @@ func (s *scanner) Scan(filterStruct interface{},
 		fieldValue := rv.Field(i)
 		fieldType := rt.Field(i)
 
+		// the sort specification and the pagination are not filtered by,
+		// they are scanned by ScanSort and ScanPagination
+		if s.reserved(context, fieldType) {
+			continue
+		}
+
 		// if the field is a pointer, get the value and type of the field
 		if fieldValue.Kind() == reflect.Ptr {
+			// nil pointers are not set by the user and are not filtered by, so that the optional
+			// hops of a relation chain (e.g. the industry of a company) can be left out
+			// (their zero value can not be filtered by: it has no type and no operator tag)
+			if fieldValue.IsNil() {
+				continue
+			}
 			fieldValue = fieldValue.Elem()
 		}
 
-		// if the field is a struct, recursively call Scan
-		if fieldValue.Kind() == reflect.Struct {
-			fields, err := s.Scan(fieldValue.Interface(), &fieldType, index)
+		// if the field is a struct, recursively call scan
+		// (the structs stored as a single value, e.g. time.Time, are filtered by as any other field)
+		if nestedStruct(fieldValue.Type()) {
+			nested, err := s.nestedContext(context, fieldType)
+			if err != nil {
+				return nil, err
+			}
+
+			fields, err := s.scan(nested, fieldValue.Interface(), index)
 			if err != nil {
 				return nil, err
 			}
@@ -98,7 +671,7 @@ func (s *scanner) Scan(filterStruct interface{},
 		}
 
 		// create a new filter field
-		fields, err := s.makeField(collection, fieldValue, fieldType, parentField, index)
+		fields, err := s.makeField(context, fieldValue, fieldType, index)
 
 		// if field could not be created, return error (validation error or unsupported field type)
 		if err != nil {
@@ -114,6 +687,102 @@ func (s *scanner) Scan(filterStruct interface{},
 	return filterFields, nil
 }
 
+// nestedContext returns the context the fields of a nested struct are scanned with.
+// The lookup names of the nested fields are nested under the lookup name of the struct field,
+// unless it is a relation (then they are nested under the key the joined document is stored at)
+// or a group container (then they are not nested at all, the struct only groups them logically).
+func (s *scanner) nestedContext(context scanContext, reflectionType reflect.StructField) (scanContext, error) {
+	nested := context
+	nested.path = context.path + s.lookupName(reflectionType) + "."
+	nested.nested = true
+
+	// if the struct is a relation, its fields are in another collection
+	// and are nested under the key the joined document is stored at
+	if relationTagValue := reflectionType.Tag.Get(s.relationTagName); relationTagValue != "" {
+		relation, err := s.makeRelation(relationTagValue, s.lookupName(reflectionType), context.relation)
+		if err != nil {
+			return nested, err
+		}
+		nested.collection, nested.path, nested.relation = relation.Collection, relation.As+".", relation
+		return nested, nil
+	}
+
+	// if the struct is a group container, all of its fields belong to the group
+	if groupTagValue := reflectionType.Tag.Get(s.groupTagName); groupTagValue != "" {
+		group, err := s.makeGroup(context, groupTagValue)
+		if err != nil {
+			return nested, err
+		}
+		nested.path, nested.group = context.path, group
+	}
+	return nested, nil
+}
+
+// lookupName returns the lookup tag value of the struct field
+// or the struct field name if the lookup tag value is empty
+func (s *scanner) lookupName(reflectionType reflect.StructField) string {
+	if lookupTagValue := reflectionType.Tag.Get(s.lookupTagName); lookupTagValue != "" {
+		return lookupTagValue
+	}
+	return reflectionType.Name
+}
+
+// makeRelation parses the relation tag value and fills the missing keys with defaults:
+// the local and foreign fields default to the lookup name of the struct field
+// and the joined document is stored under the name of the joined collection.
+// If the relation is declared inside of another relation, it is joined through it
+// and its local field and `as` key are nested under the key of the parent.
+func (s *scanner) makeRelation(relationTagValue string, lookupName string,
+	parent *field.Relation) (*field.Relation, error) {
+	relation, err := field.ParseRelation(relationTagValue)
+	if err != nil {
+		return nil, err
+	}
+	if relation.LocalField == "" {
+		relation.LocalField = lookupName
+	}
+	if relation.ForeignField == "" {
+		relation.ForeignField = lookupName
+	}
+	if relation.As == "" {
+		relation.As = relation.Collection
+	}
+	if parent != nil {
+		relation.Parent = parent
+		relation.LocalField = parent.As + "." + relation.LocalField
+		relation.As = parent.As + "." + relation.As
+	}
+	return relation, nil
+}
+
+// makeGroup parses the group tag value and returns the group with this name
+// nested in the group of the context. Fields naming the same group share it,
+// so only one of them has to name the merge policy combining its fields,
+// but the ones that do have to agree on it.
+func (s *scanner) makeGroup(context scanContext, groupTagValue string) (*field.Group, error) {
+	group, err := field.ParseGroup(groupTagValue)
+	if err != nil {
+		return nil, err
+	}
+	group.Parent = context.group
+
+	key := groupKey{parent: context.group, name: group.Name}
+	existing, exists := context.groups[key]
+	if !exists {
+		context.groups[key] = group
+		return group, nil
+	}
+
+	if existing.Method == "" {
+		existing.Method = group.Method
+	}
+	if group.Method != "" && group.Method != existing.Method {
+		return nil, errors.Errorf("group %s has conflicting methods %s and %s",
+			group.Name, existing.Method, group.Method)
+	}
+	return existing, nil
+}
+
 // makeField creates a new filter field from provided struct field
 // It does not validate the field, it only creates a new filter field
 // The only validation it does is validation against tag values correctness
@@ -121,29 +790,40 @@ func (s *scanner) Scan(filterStruct interface{},
 // or if operator tag value is empty, it returns error
 // or if the operator tag provided is not supported (does not exist in opmap),
 // it returns error
-func (s *scanner) makeField(collection string, reflectionValue reflect.Value,
-	reflectionType reflect.StructField, parentField *reflect.StructField, index int) (field.IFilterField, error) {
+func (s *scanner) makeField(context scanContext, reflectionValue reflect.Value,
+	reflectionType reflect.StructField, index int) (field.IFilterField, error) {
 	// get the tag value of the field
-	lookupTagValue := reflectionType.Tag.Get(s.lookupTagName)
+	lookupTagValue := s.lookupName(reflectionType)
 	relationTagValue := reflectionType.Tag.Get(s.relationTagName)
 	operatorTagValue := reflectionType.Tag.Get(s.operatorTagName)
+	groupTagValue := reflectionType.Tag.Get(s.groupTagName)
+	collection, path, relation, group := context.collection, context.path, context.relation, context.group
 
 	// if there is a relation tag, then the field is in another collection
+	// and the lookup value is nested under the key the joined document is stored at
 	if relationTagValue != "" {
-		collection = relationTagValue
+		var err error
+		relation, err = s.makeRelation(relationTagValue, lookupTagValue, relation)
+		if err != nil {
+			return nil, err
+		}
+		collection, path = relation.Collection, relation.As+"."
 	}
 
-	// if there is a parent field,
-	// combine the parent field name and the current field name
-	// to get the lookup value
-	if parentField != nil {
-		if parentLookupName := parentField.Tag.Get(s.lookupTagName); parentLookupName != "" {
-			lookupTagValue = parentLookupName + "." + lookupTagValue
-		} else {
-			lookupTagValue = parentField.Name + "." + lookupTagValue
+	// if there is a group tag, then the field belongs to the group
+	// nested in the group of the struct (if any)
+	if groupTagValue != "" {
+		var err error
+		group, err = s.makeGroup(context, groupTagValue)
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	// combine the path of the parent fields and
+	// the current field name to get the lookup value
+	lookupTagValue = path + lookupTagValue
+
 	// get operator from operator map
 	op := s.operatorMap.Get(operatorTagValue)
 
@@ -152,11 +832,6 @@ func (s *scanner) makeField(collection string, reflectionValue reflect.Value,
 		return nil, errors.Errorf("operator %s is not supported", operatorTagValue)
 	}
 
-	// if lookup tag value is empty, get the field name
-	if lookupTagValue == "" {
-		lookupTagValue = reflectionType.Name
-	}
-
 	for _, valid := range s.validators {
 		if err := valid.Validate(reflectionValue, reflectionType); err != nil {
 			return nil, err
@@ -171,10 +846,24 @@ func (s *scanner) makeField(collection string, reflectionValue reflect.Value,
 		op,
 		index,
 	)
+	if relation != nil {
+		filterField.SetRelation(relation)
+	}
+	if group != nil {
+		filterField.SetGroup(group)
+	}
+	if mergeTagValue := reflectionType.Tag.Get(s.mergeTagName); mergeTagValue != "" {
+		filterField.SetMergeMethod(mergeTagValue)
+	}
 	return filterField, nil
 }
 
 // NewScanner creates new scanner instance with provided options. Factory method.
+// Duplicate fields name their merge policy in the `merge` tag by default
+// and fields name their logical group in the `group` tag by default.
+// The sort specification is held by the field with the `sort` lookup name by default
+// and the pagination by the fields with the `page`, `pageSize`, `limit` and `offset` lookup names
+// (unless they have an operator tag, then they are filtered by as any other field).
 func NewScanner(operatorMap operator.IOperatorMap,
 	validators []validator.IValidator,
 	lookupTagName string,
@@ -185,5 +874,12 @@ func NewScanner(operatorMap operator.IOperatorMap,
 		lookupTagName:   lookupTagName,
 		operatorTagName: operatorTagName,
 		relationTagName: relationTagName,
+		mergeTagName:    "merge",
+		groupTagName:    "group",
+		sortName:        "sort",
+		pageName:        "page",
+		pageSizeName:    "pageSize",
+		limitName:       "limit",
+		offsetName:      "offset",
 	}
 }
@@ -1,11 +1,18 @@
 package scanner
 
 import (
+	"github.com/jobsearch-demos/mongo-filter-struct/builder"
 	"github.com/jobsearch-demos/mongo-filter-struct/field"
 	"github.com/jobsearch-demos/mongo-filter-struct/operator"
+	"github.com/jobsearch-demos/mongo-filter-struct/paginator"
 	"github.com/jobsearch-demos/mongo-filter-struct/validator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type TestStructWithIntPointer struct {
@@ -136,6 +143,66 @@ func (t TestStructWithCollectionName) CollectionName() string {
 	return "users"
 }
 
+type TestStructWithRelation struct {
+	CompanyName string `json:"companyName" bson:"companyName" filter:"name" operator:"eq" join:"companies,local=companyId,foreign=_id,as=company"`
+}
+
+type TestStructWithDefaultRelation struct {
+	CompanyID string `json:"companyId" bson:"companyId" filter:"companyId" operator:"eq" join:"companies"`
+}
+
+type TestStructWithRelationStruct struct {
+	Company TestStructWithString `json:"company" bson:"company" join:"companies,local=companyId,foreign=_id,as=company"`
+}
+
+type TestStructWithIndustry struct {
+	Name string `json:"name" bson:"name" filter:"name" operator:"eq"`
+}
+
+type TestStructWithCompany struct {
+	Name     *string                 `json:"name" bson:"name" filter:"name" operator:"eq"`
+	Industry *TestStructWithIndustry `json:"industry" bson:"industry" join:"industries,local=industryId,foreign=_id,as=industry"`
+}
+
+type TestStructWithRelationChain struct {
+	Company *TestStructWithCompany `json:"company" bson:"company" join:"companies,local=companyId,foreign=_id,as=company"`
+}
+
+type TestStructWithMergeMethod struct {
+	Age int `json:"age" bson:"age" filter:"age" operator:"eq" merge:"or"`
+}
+
+type TestStructWithInvalidRelation struct {
+	CompanyName string `json:"companyName" bson:"companyName" filter:"name" operator:"eq" join:"companies,on=companyId"`
+}
+
+type TestStructWithGroup struct {
+	Remote bool   `json:"remote" bson:"remote" filter:"remote" operator:"eq" group:"location,or"`
+	City   string `json:"city" bson:"city" filter:"city" operator:"eq" group:"location"`
+}
+
+type TestStructWithLocation struct {
+	Remote bool   `json:"remote" bson:"remote" filter:"remote" operator:"eq"`
+	City   string `json:"city" bson:"city" filter:"city" operator:"eq" group:"office"`
+}
+
+type TestStructWithGroupContainer struct {
+	Location TestStructWithLocation `json:"location" bson:"location" group:"location,or"`
+}
+
+type TestStructWithConflictingGroup struct {
+	Remote bool   `json:"remote" bson:"remote" filter:"remote" operator:"eq" group:"location,or"`
+	City   string `json:"city" bson:"city" filter:"city" operator:"eq" group:"location,and"`
+}
+
+type TestStructWithNestedStructAndCollectionName struct {
+	User TestStructWithInt `json:"user" bson:"user" filter:"user" operator:"eq"`
+}
+
+func (t TestStructWithNestedStructAndCollectionName) CollectionName() string {
+	return "users"
+}
+
 func TestScanner_Scan(t *testing.T) {
 	integer := 73
 	integerPointer := &integer
@@ -592,6 +659,212 @@ func TestScanner_Scan(t *testing.T) {
 					operator.EQOperator{}, 0),
 			},
 		},
+		{
+			name: "Scan struct with relation",
+			strct: TestStructWithRelation{
+				CompanyName: stringValue,
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("companies",
+					reflect.String.String(),
+					"company.name", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+			},
+		},
+		{
+			name: "Scan struct with relation without join keys",
+			strct: TestStructWithDefaultRelation{
+				CompanyID: stringValue,
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("companies",
+					reflect.String.String(),
+					"companies.companyId", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "companyId", "companies")),
+			},
+		},
+		{
+			name: "Scan struct with relation struct",
+			strct: TestStructWithRelationStruct{
+				Company: TestStructWithString{
+					Name: stringValue,
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("companies",
+					reflect.String.String(),
+					"company.name", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+			},
+		},
+		{
+			name: "Scan struct with relation chain",
+			strct: TestStructWithRelationChain{
+				Company: &TestStructWithCompany{
+					Name: stringPointer,
+					Industry: &TestStructWithIndustry{
+						Name: stringValue,
+					},
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("companies",
+					reflect.String.String(),
+					"company.name", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+				field.NewFilterField("industries",
+					reflect.String.String(),
+					"company.industry.name", stringValue,
+					operator.EQOperator{}, 1).
+					SetRelation(&field.Relation{
+						Collection:   "industries",
+						LocalField:   "company.industryId",
+						ForeignField: "_id",
+						As:           "company.industry",
+						Parent:       field.NewRelation("companies", "companyId", "_id", "company"),
+					}),
+			},
+		},
+		{
+			name: "Scan struct with a nil hop of a relation chain",
+			strct: TestStructWithRelationChain{
+				Company: &TestStructWithCompany{
+					Name: stringPointer,
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("companies",
+					reflect.String.String(),
+					"company.name", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(field.NewRelation("companies", "companyId", "_id", "company")),
+			},
+		},
+		{
+			name:    "Scan struct with a nil relation chain",
+			strct:   TestStructWithRelationChain{},
+			wantErr: false,
+			want:    nil,
+		},
+		{
+			name: "Scan struct with nil pointers",
+			strct: TestStructWithRelationChain{
+				Company: &TestStructWithCompany{
+					Industry: &TestStructWithIndustry{
+						Name: stringValue,
+					},
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("industries",
+					reflect.String.String(),
+					"company.industry.name", stringValue,
+					operator.EQOperator{}, 0).
+					SetRelation(&field.Relation{
+						Collection:   "industries",
+						LocalField:   "company.industryId",
+						ForeignField: "_id",
+						As:           "company.industry",
+						Parent:       field.NewRelation("companies", "companyId", "_id", "company"),
+					}),
+			},
+		},
+		{
+			name: "Scan struct with merge method",
+			strct: TestStructWithMergeMethod{
+				Age: integer,
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("",
+					reflect.Int.String(),
+					"age", integer,
+					operator.EQOperator{}, 0).
+					SetMergeMethod("or"),
+			},
+		},
+		{
+			name: "Scan struct with invalid relation",
+			strct: TestStructWithInvalidRelation{
+				CompanyName: stringValue,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Scan struct with group",
+			strct: TestStructWithGroup{
+				Remote: boolean,
+				City:   stringValue,
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("",
+					reflect.Bool.String(),
+					"remote", boolean,
+					operator.EQOperator{}, 0).
+					SetGroup(field.NewGroup("location", "or", nil)),
+				field.NewFilterField("",
+					reflect.String.String(),
+					"city", stringValue,
+					operator.EQOperator{}, 1).
+					SetGroup(field.NewGroup("location", "or", nil)),
+			},
+		},
+		{
+			name: "Scan struct with group container",
+			strct: TestStructWithGroupContainer{
+				Location: TestStructWithLocation{
+					Remote: boolean,
+					City:   stringValue,
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("",
+					reflect.Bool.String(),
+					"remote", boolean,
+					operator.EQOperator{}, 0).
+					SetGroup(field.NewGroup("location", "or", nil)),
+				field.NewFilterField("",
+					reflect.String.String(),
+					"city", stringValue,
+					operator.EQOperator{}, 1).
+					SetGroup(field.NewGroup("office", "", field.NewGroup("location", "or", nil))),
+			},
+		},
+		{
+			name: "Scan struct with conflicting group methods",
+			strct: TestStructWithConflictingGroup{
+				Remote: boolean,
+				City:   stringValue,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Scan nested struct without collection name",
+			strct: TestStructWithNestedStructAndCollectionName{
+				User: TestStructWithInt{
+					Age: integer,
+				},
+			},
+			wantErr: false,
+			want: []field.IFilterField{
+				field.NewFilterField("users",
+					reflect.Int.String(),
+					"user.age", integer,
+					operator.EQOperator{}, 0),
+			},
+		},
 	}
 	// create validators
 	opValidator := validator.NewOperatorValidator(operator.NewOperatorMap(), "operator")
@@ -610,6 +883,10 @@ func TestScanner_Scan(t *testing.T) {
 				t.Errorf("Scan() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if !tt.wantErr && len(got) != len(tt.want) {
+				t.Errorf("Scan() got %d fields, want %d", len(got), len(tt.want))
+				return
+			}
 			for i, v := range got {
 				if !reflect.DeepEqual(v, tt.want[i]) {
 					t.Errorf("Scan() got = %v, want %v", v, tt.want[i])
@@ -618,3 +895,424 @@ func TestScanner_Scan(t *testing.T) {
 		})
 	}
 }
+
+type TestStructWithSingleValueStructs struct {
+	PostedAt *time.Time          `json:"postedAt" bson:"postedAt" filter:"postedAt" operator:"eq"`
+	Version  primitive.Timestamp `json:"version" bson:"version" filter:"version" operator:"eq"`
+}
+
+func TestScanner_Scan_SingleValueStructs(t *testing.T) {
+	postedAt := time.Date(2022, 10, 29, 12, 0, 0, 0, time.UTC)
+	version := primitive.Timestamp{T: 1667044800, I: 1}
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+
+	// the structs stored as a single value are filtered by as any other field
+	fields, err := scan.Scan(TestStructWithSingleValueStructs{PostedAt: &postedAt, Version: version}, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []field.IFilterField{
+		field.NewFilterField("", reflect.Struct.String(), "postedAt", postedAt, operator.EQOperator{}, 0),
+		field.NewFilterField("", reflect.Struct.String(), "version", version, operator.EQOperator{}, 1),
+	}, fields)
+}
+
+type TestStructWithSort struct {
+	Title   string                `json:"title" bson:"title" filter:"title" operator:"eq"`
+	Posted  *int                  `json:"postedAt" bson:"postedAt" filter:"postedAt" operator:"gte"`
+	Company *TestStructWithString `json:"company" bson:"company" join:"companies,local=companyId,foreign=_id,as=company"`
+	Sort    []string              `json:"sort" bson:"sort" filter:"sort"`
+}
+
+type TestStructWithStringSort struct {
+	Title string `json:"title" bson:"title" filter:"title" operator:"eq"`
+	Sort  string `json:"sort" bson:"sort" filter:"sort"`
+}
+
+type TestStructWithTimeSort struct {
+	Title    string     `json:"title" bson:"title" filter:"title" operator:"eq"`
+	PostedAt *time.Time `json:"postedAt" bson:"postedAt" filter:"postedAt" operator:"gte"`
+	Sort     string     `json:"sort" bson:"sort" filter:"sort"`
+}
+
+type TestStructWithInvalidSort struct {
+	Title string `json:"title" bson:"title" filter:"title" operator:"eq"`
+	Sort  int    `json:"sort" bson:"sort" filter:"sort"`
+}
+
+func TestScanner_ScanSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		strct   interface{}
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			name:  "Scan struct without sort specification",
+			strct: TestStructWithSort{Title: "golang"},
+			want:  bson.D{},
+		},
+		{
+			name:  "Scan struct with sort specification list",
+			strct: TestStructWithSort{Sort: []string{"-postedAt", "company.name,title"}},
+			want: bson.D{
+				{Key: "postedAt", Value: -1},
+				{Key: "company.name", Value: 1},
+				{Key: "title", Value: 1},
+			},
+		},
+		{
+			name:  "Scan struct with sort specification string",
+			strct: &TestStructWithStringSort{Sort: "-title"},
+			want:  bson.D{{Key: "title", Value: -1}},
+		},
+		{
+			name:  "Scan struct with sort key of a time field",
+			strct: TestStructWithTimeSort{Sort: "-postedAt,title"},
+			want:  bson.D{{Key: "postedAt", Value: -1}, {Key: "title", Value: 1}},
+		},
+		{
+			name:    "Scan struct with sort key which is not a field",
+			strct:   TestStructWithSort{Sort: []string{"salary"}},
+			wantErr: true,
+		},
+		{
+			name:    "Scan struct with sort field which is not a string",
+			strct:   TestStructWithInvalidSort{Sort: 1},
+			wantErr: true,
+		},
+	}
+
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scan.ScanSort(tt.strct)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanner_Scan_Sort(t *testing.T) {
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+
+	// the sort specification is not a filter field
+	fields, err := scan.Scan(TestStructWithSort{Title: "golang", Sort: []string{"-postedAt"}}, nil, 0)
+	assert.NoError(t, err)
+	assert.Len(t, fields, 1)
+
+	// but invalid sort keys are reported the same way as invalid filters
+	_, err = scan.Scan(TestStructWithSort{Title: "golang", Sort: []string{"salary"}}, nil, 0)
+	assert.Error(t, err)
+}
+
+type TestStructWithPage struct {
+	Title    string `json:"title" bson:"title" filter:"title" operator:"eq"`
+	Page     int    `json:"page" bson:"page" filter:"page"`
+	PageSize *uint  `json:"pageSize" bson:"pageSize" filter:"pageSize"`
+}
+
+type TestStructWithOffset struct {
+	Title  string `json:"title" bson:"title" filter:"title" operator:"eq"`
+	Limit  int64  `json:"limit" bson:"limit" filter:"limit"`
+	Offset int64  `json:"offset" bson:"offset" filter:"offset"`
+	Page   int64  `json:"page" bson:"page" filter:"page"`
+}
+
+func TestScanner_ScanPagination(t *testing.T) {
+	pageSize := uint(10)
+
+	tests := []struct {
+		name    string
+		strct   interface{}
+		want    paginator.Pagination
+		wantErr bool
+	}{
+		{
+			name:  "Scan struct with page and page size",
+			strct: TestStructWithPage{Page: 3, PageSize: &pageSize},
+			want:  paginator.Pagination{Skip: 20, Limit: 10},
+		},
+		{
+			name:  "Scan struct without pagination",
+			strct: TestStructWithPage{Title: "golang"},
+			want:  paginator.Pagination{Skip: 0, Limit: 50},
+		},
+		{
+			name:  "Scan struct with limit and offset",
+			strct: &TestStructWithOffset{Limit: 1000, Offset: 30},
+			want:  paginator.Pagination{Skip: 30, Limit: 100},
+		},
+		{
+			name:    "Scan struct with both page and offset",
+			strct:   TestStructWithOffset{Page: 2, Offset: 30},
+			wantErr: true,
+		},
+		{
+			name:    "Scan struct with negative page",
+			strct:   TestStructWithPage{Page: -1},
+			wantErr: true,
+		},
+	}
+
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scan.ScanPagination(tt.strct, paginator.NewPaginator(50, 100))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type TestStructWithFilteredLimit struct {
+	Limit    int   `json:"limit" bson:"limit" filter:"limit" operator:"gte"`
+	PageSize int64 `json:"pageSize" bson:"pageSize" filter:"pageSize"`
+}
+
+func TestScanner_Scan_Pagination(t *testing.T) {
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+
+	// the pagination fields are not filter fields
+	fields, err := scan.Scan(TestStructWithOffset{Title: "golang", Limit: 10, Offset: 20}, nil, 0)
+	assert.NoError(t, err)
+	assert.Len(t, fields, 1)
+
+	// unless they have an operator tag
+	filtered := TestStructWithFilteredLimit{Limit: 5, PageSize: 20}
+	fields, err = scan.Scan(filtered, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []field.IFilterField{
+		field.NewFilterField("", reflect.Int.String(), "limit", 5, operator.GTEOperator{}, 0),
+	}, fields)
+
+	schema, err := scan.ScanSchema(filtered)
+	assert.NoError(t, err)
+	assert.Len(t, schema, 1)
+
+	pagination, err := scan.ScanPagination(filtered, paginator.NewPaginator(10, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, paginator.Pagination{Skip: 0, Limit: 20}, pagination)
+}
+
+func TestScanner_Scan_Pipeline(t *testing.T) {
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+
+	// the fields of a struct with a collection name but without relations are not joined,
+	// even if the builder is not told the collection
+	fields, err := scan.Scan(TestStructWithCollectionName{Age: 30}, nil, 0)
+	assert.NoError(t, err)
+
+	pipeline := builder.NewFilterBuilder().SetFields(fields).Build().Pipeline()
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "age", Value: bson.D{{Key: "$eq", Value: 30}}}}}},
+	}, pipeline)
+}
+
+type TestCompanyResponse struct {
+	Name    string `bson:"name"`
+	Size    int    `bson:"size,omitempty"`
+	Secret  string `bson:"-"`
+	private string
+}
+
+type TestSalaryResponse struct {
+	Min int `bson:"min"`
+	Max int `bson:"max"`
+}
+
+type TestJobResponse struct {
+	ID       string               `bson:"_id"`
+	Title    string               `filter:"title" bson:"jobTitle"`
+	PostedAt time.Time            `bson:"postedAt"`
+	Salary   *TestSalaryResponse  `bson:"salary"`
+	Company  *TestCompanyResponse `bson:"company" join:"companies,local=companyId,foreign=_id,as=company"`
+	Industry string               `bson:"name" join:"industries,local=industryId,foreign=_id,as=industry"`
+}
+
+type TestAuditResponse struct {
+	CreatedBy string    `bson:"createdBy"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+type TestPostResponse struct {
+	Title    string
+	Audit    TestAuditResponse `bson:",inline"`
+	PostedAt time.Time
+}
+
+func TestScanner_ScanProjection(t *testing.T) {
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+
+	got, err := scan.ScanProjection(&TestJobResponse{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"_id", "title", "postedAt", "salary.min", "salary.max",
+		"company.name", "company.size", "industry.name",
+	}, got.Paths())
+	assert.Equal(t, []field.Relation{
+		*field.NewRelation("companies", "companyId", "_id", "company"),
+		*field.NewRelation("industries", "industryId", "_id", "industry"),
+	}, got.Relations())
+
+	parent := reflect.StructField{Name: "Job", Tag: `bson:"job"`}
+	got, err = scan.ScanProjection(TestSalaryResponse{}, &parent)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job.min", "job.max"}, got.Paths())
+
+	// the names default to the lowercased field names and inline structs are flattened
+	got, err = scan.ScanProjection(TestPostResponse{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"title", "createdBy", "updatedAt", "postedat"}, got.Paths())
+
+	_, err = scan.ScanProjection("not a struct", nil)
+	assert.Error(t, err)
+}
+
+type TestSavedSearch struct {
+	Title     *string                `json:"title" bson:"title" filter:"title" operator:"regex"`
+	SalaryMin *int                   `json:"salaryMin" bson:"salaryMin" filter:"salary" operator:"gte"`
+	SalaryMax *int                   `json:"salaryMax" bson:"salaryMax" filter:"salary" operator:"lte"`
+	Tags      []string               `json:"tags" bson:"tags" filter:"tags" operator:"in"`
+	Company   *TestStructWithCompany `json:"company" bson:"company" join:"companies,local=companyId,foreign=_id,as=company"`
+	Remote    *bool                  `json:"remote" bson:"remote" filter:"remote" operator:"eq" group:"location,or"`
+	City      *string                `json:"city" bson:"city" filter:"city" operator:"eq" group:"location"`
+	Sort      []string               `json:"sort" bson:"sort" filter:"sort"`
+}
+
+func TestScanner_Hydrate(t *testing.T) {
+	title, acme, city, remote := "golang", "Acme", "Baku", true
+	salaryMin, salaryMax := 1000, 5000
+	saved := TestSavedSearch{
+		Title:     &title,
+		SalaryMin: &salaryMin,
+		SalaryMax: &salaryMax,
+		Tags:      []string{"go", "mongo"},
+		Company:   &TestStructWithCompany{Name: &acme},
+		Remote:    &remote,
+		City:      &city,
+	}
+
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+	fields, err := scan.Scan(saved, nil, 0)
+	assert.NoError(t, err)
+	filter := builder.NewFilterBuilder().SetFields(fields).MergeDuplicateFields().Build()
+	assert.NoError(t, filter.Err())
+
+	// the struct the filter is built from is populated back from it
+	var hydrated TestSavedSearch
+	assert.NoError(t, scan.Hydrate(filter.Output(), &hydrated))
+	assert.Equal(t, saved, hydrated)
+
+	tests := []struct {
+		name    string
+		filter  bson.D
+		want    TestSavedSearch
+		wantErr string
+	}{
+		{
+			name:   "Implicit eq",
+			filter: bson.D{{Key: "company.name", Value: "Acme"}},
+			want:   TestSavedSearch{Company: &TestStructWithCompany{Name: &acme}},
+		},
+		{
+			name: "Fields merged by and",
+			filter: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$gte", Value: int64(1000)}}}},
+				bson.D{{Key: "salary", Value: bson.D{{Key: "$lte", Value: 5000.0}}}},
+			}}},
+			want: TestSavedSearch{SalaryMin: &salaryMin, SalaryMax: &salaryMax},
+		},
+		{
+			name: "Unknown fields, operators and types are reported while the rest is mapped",
+			filter: bson.D{
+				{Key: "title", Value: bson.D{{Key: "$regex", Value: "golang"}, {Key: "$options", Value: "i"}}},
+				{Key: "views", Value: bson.D{{Key: "$gt", Value: 10}}},
+				{Key: "salary", Value: bson.D{{Key: "$gte", Value: "a lot"}}},
+				{Key: "$expr", Value: bson.D{}},
+			},
+			want:    TestSavedSearch{Title: &title},
+			wantErr: "filter parts could not be mapped: title.$options, views.$gt, salary.$gte, $expr",
+		},
+		{
+			name: "Conditions are only mapped to fields combined the same way",
+			filter: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "remote", Value: bson.D{{Key: "$eq", Value: true}}}},
+				bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "golang"}}}},
+			}}},
+			want:    TestSavedSearch{Remote: &remote},
+			wantErr: "filter parts could not be mapped: $or.1.title.$regex",
+		},
+		{
+			name: "Conditions are mapped to a field once",
+			filter: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "golang"}}}},
+				bson.D{{Key: "title", Value: bson.D{{Key: "$regex", Value: "mongo"}}}},
+			}}},
+			want:    TestSavedSearch{Title: &title},
+			wantErr: "filter parts could not be mapped: $and.1.title.$regex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TestSavedSearch
+			err := scan.Hydrate(tt.filter, &got)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Error(t, scan.Hydrate(bson.D{}, TestSavedSearch{}))
+}
+
+type TestStructWithXor struct {
+	City  *string `json:"city" bson:"city" filter:"city" operator:"eq" merge:"xor"`
+	Other *string `json:"other" bson:"other" filter:"city" operator:"ne" merge:"xor"`
+}
+
+func TestScanner_Hydrate_UnsupportedMergeMethods(t *testing.T) {
+	city, other := "Baku", "Ganja"
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+	fields, err := scan.Scan(TestStructWithXor{City: &city, Other: &other}, nil, 0)
+	assert.NoError(t, err)
+	filter := builder.NewFilterBuilder().SetFields(fields).MergeDuplicateFields().Build()
+	assert.NoError(t, filter.Err())
+
+	// the fields merged by xor (or not) are never mapped, so all of their conditions are reported
+	var hydrated TestStructWithXor
+	assert.EqualError(t, scan.Hydrate(filter.Output(), &hydrated), "filter parts could not be mapped: "+
+		"$or.0.$and.0.city.$eq, $or.0.$and.1.$nor.0.city.$ne, $or.1.$and.0.$nor.0.city.$eq, $or.1.$and.1.city.$ne")
+	assert.Equal(t, TestStructWithXor{}, hydrated)
+}
+
+func TestScanner_ScanSchema(t *testing.T) {
+	scan := NewScanner(operator.NewOperatorMap(), nil, "filter", "operator", "join")
+	schema, err := scan.ScanSchema(TestSavedSearch{})
+	assert.NoError(t, err)
+
+	var names, types []string
+	for _, schemaField := range schema {
+		names = append(names, schemaField.GetName())
+		types = append(types, schemaField.GetType())
+	}
+	assert.Equal(t, []string{"title", "salary", "salary", "tags", "company.name", "company.industry.name", "remote", "city"}, names)
+	assert.Equal(t, []string{"string", "int", "int", "slice", "string", "string", "bool", "string"}, types)
+	assert.Equal(t, "industries", schema[5].GetCollection())
+	assert.Equal(t, "company.industry", schema[5].GetRelation().As)
+	assert.Equal(t, "or", schema[6].GetGroup().Method)
+	assert.Equal(t, schema[6].GetGroup(), schema[7].GetGroup())
+
+	_, err = scan.ScanSchema("not a struct")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+// License: GNU General Public License v3.0
+// Author: Kamran Valijonov
+// Version: 1.0.0
+// Date: 2022-10-29
+// Description: Mongo Filter Builder
+// This tool is used to build bson filter for mongodb based on provided struct.
+// Motivation: I was tired of writing bson.M{} for every query and wanted
+// something more elegant and easy to use like django-filter.
+
+package sorter
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ISorter is used to build the sort document of a query from a sort specification,
+// e.g. "-postedAt,title" is built into {postedAt: -1, title: 1}.
+// Only the whitelisted (sortable) lookup names can be sorted by,
+// so that the specification can come straight from the user (e.g. a query string).
+type ISorter interface {
+	// Sort parses the sort specification and returns the sort document
+	Sort(specification ...string) (bson.D, error)
+
+	// IsSortable checks whether the lookup name can be sorted by
+	IsSortable(name string) bool
+}
+
+type sorter struct {
+	sortable map[string]bool
+}
+
+// Sort parses the sort specification and returns the sort document.
+// Every element of the specification is a comma separated list of lookup names,
+// each of them prefixed with `-` for descending order (and optionally `+` for ascending order).
+// It returns error if a key has more than one prefix or if a lookup name is not sortable or repeats.
+func (s *sorter) Sort(specification ...string) (bson.D, error) {
+	sort := bson.D{}
+	used := map[string]bool{}
+
+	for _, element := range specification {
+		for _, key := range strings.Split(element, ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+
+			order, name := 1, key
+			switch key[0] {
+			case '-':
+				order, name = -1, key[1:]
+			case '+':
+				name = key[1:]
+			}
+
+			if name == "" || name[0] == '-' || name[0] == '+' {
+				return nil, errors.Errorf("sort key %s is not valid", key)
+			}
+			if !s.IsSortable(name) {
+				return nil, errors.Errorf("sort key %s is not sortable", name)
+			}
+			if used[name] {
+				return nil, errors.Errorf("sort key %s is repeated", name)
+			}
+			used[name] = true
+			sort = append(sort, bson.E{Key: name, Value: order})
+		}
+	}
+	return sort, nil
+}
+
+// IsSortable checks whether the lookup name can be sorted by
+func (s *sorter) IsSortable(name string) bool {
+	return s.sortable[name]
+}
+
+// NewSorter creates a new sorter allowing to sort by the provided lookup names
+func NewSorter(sortable []string) ISorter {
+	s := &sorter{sortable: map[string]bool{}}
+	for _, name := range sortable {
+		s.sortable[name] = true
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package sorter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSorter_Sort(t *testing.T) {
+	tests := []struct {
+		name          string
+		specification []string
+		want          bson.D
+		wantErr       bool
+	}{
+		{
+			name: "Empty specification yields an empty sort document",
+			want: bson.D{},
+		},
+		{
+			name:          "Keys are sorted ascending unless prefixed with -",
+			specification: []string{"-postedAt, title", "+company.name"},
+			want: bson.D{
+				{Key: "postedAt", Value: -1},
+				{Key: "title", Value: 1},
+				{Key: "company.name", Value: 1},
+			},
+		},
+		{
+			name:          "Empty keys are skipped",
+			specification: []string{"title,,", ""},
+			want:          bson.D{{Key: "title", Value: 1}},
+		},
+		{
+			name:          "Keys which are not sortable are rejected",
+			specification: []string{"-password"},
+			wantErr:       true,
+		},
+		{
+			name:          "Keys with several prefixes are rejected",
+			specification: []string{"+-title"},
+			wantErr:       true,
+		},
+		{
+			name:          "Keys with a repeated prefix are rejected",
+			specification: []string{"--title"},
+			wantErr:       true,
+		},
+		{
+			name:          "Prefixes without a key are rejected",
+			specification: []string{"-"},
+			wantErr:       true,
+		},
+		{
+			name:          "Repeated keys are rejected",
+			specification: []string{"title,-title"},
+			wantErr:       true,
+		},
+	}
+
+	sorter := NewSorter([]string{"postedAt", "title", "company.name"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sorter.Sort(tt.specification...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}